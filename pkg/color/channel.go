@@ -1,5 +1,7 @@
 package color
 
+import "math"
+
 type Channel int
 
 const (
@@ -7,21 +9,94 @@ const (
 	ChannelGreen
 	ChannelBlue
 	ChannelGray
+	// The derived channels are computed from red, green and blue and scaled
+	// to the 16-bit depth: hue maps [0, 360) degrees, saturation, value and
+	// lightness map [0, 1], L* maps [0, 100] and a*, b* map [-128, 127].
+	// Hue is circular, so its sums and means only hold for regions away from
+	// red, where 2 and 358 degrees average to cyan: area statistics of hue
+	// are read from ChannelHueX and ChannelHueY instead.
+	ChannelHue
+	ChannelSaturation
+	ChannelValue
+	ChannelHSLSaturation
+	ChannelLightness
+	ChannelLabL
+	ChannelLabA
+	ChannelLabB
+	// ChannelHueX and ChannelHueY are the HSV saturation times the cosine
+	// and the sine of the hue, mapping [-1, 1]: the hue as a point of the
+	// color wheel, gray at its center, which MeanHue reads means back from.
+	ChannelHueX
+	ChannelHueY
 )
 
 func Channels() []Channel {
 	return []Channel{ChannelRed, ChannelGreen, ChannelBlue}
 }
 
+// DerivedChannels lists the channels Derive computes.
+func DerivedChannels() []Channel {
+	return []Channel{
+		ChannelHue, ChannelSaturation, ChannelValue,
+		ChannelHSLSaturation, ChannelLightness,
+		ChannelLabL, ChannelLabA, ChannelLabB,
+		ChannelHueX, ChannelHueY,
+	}
+}
+
 func (c Channel) String() string {
+	if name := c.Name(); name != "" {
+		return "#" + name
+	}
+	return "(unknown)"
+}
+
+// IsDerived tells whether c is one of the DerivedChannels.
+func (c Channel) IsDerived() bool {
+	return c >= ChannelHue && c <= ChannelHueY
+}
+
+// Derive computes a derived channel from 16-bit red, green and blue values,
+// scaled to the 16-bit depth. Premultiplied values are converted as they
+// are, which is exact for opaque pixels. It returns 0 for the other
+// channels.
+func Derive(c Channel, r, g, b uint16) uint16 {
+	fr, fg, fb := Normalize(r), Normalize(g), Normalize(b)
 	switch c {
-	case ChannelRed:
-		return "#red"
-	case ChannelGreen:
-		return "#green"
-	case ChannelBlue:
-		return "#blue"
-	default:
-		return "(unknown)"
+	case ChannelHue, ChannelSaturation, ChannelValue:
+		h, s, v := HSV(fr, fg, fb)
+		return scale([3]float64{h / 360, s, v}[c-ChannelHue])
+	case ChannelHSLSaturation, ChannelLightness:
+		_, s, l := HSL(fr, fg, fb)
+		return scale([2]float64{s, l}[c-ChannelHSLSaturation])
+	case ChannelLabL, ChannelLabA, ChannelLabB:
+		l, a, bb := Lab(fr, fg, fb)
+		return scale([3]float64{l / 100, (a + 128) / 255, (bb + 128) / 255}[c-ChannelLabL])
+	case ChannelHueX, ChannelHueY:
+		h, s, _ := HSV(fr, fg, fb)
+		sin, cos := math.Sincos(h * math.Pi / 180)
+		return scale(([2]float64{cos, sin}[c-ChannelHueX]*s + 1) / 2)
+	}
+	return 0
+}
+
+// MeanHue reads the means of ChannelHueX and ChannelHueY over a region, from
+// 0 to Max, back as the mean hue of the region in degrees and its strength
+// in [0, 1]: the share of saturation left once opposite hues cancel out, 0
+// for a gray region whose hue is meaningless.
+func MeanHue(x, y float64) (float64, float64) {
+	dx, dy := 2*x/Max-1, 2*y/Max-1
+	hue := math.Atan2(dy, dx) * 180 / math.Pi
+	if hue < 0 {
+		hue += 360
 	}
+	if hue >= 360 {
+		hue -= 360
+	}
+	return hue, min(math.Hypot(dx, dy), 1)
+}
+
+// scale maps [0, 1] to [0, Max], clamping what lies outside.
+func scale(v float64) uint16 {
+	return uint16(min(max(v, 0), 1)*Max + 0.5)
 }
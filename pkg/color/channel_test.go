@@ -0,0 +1,34 @@
+package color_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ramadoka/penguin-logic/pkg/color"
+)
+
+func TestMeanHue(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		rgb      [3]float64
+		hue      float64
+		strength float64
+	}{
+		{"red", [3]float64{1, 0, 0}, 0, 1},
+		{"yellow", [3]float64{1, 1, 0}, 60, 1},
+		{"dark blue", [3]float64{0, 0, 0.5}, 240, 1},
+		{"pale magenta", [3]float64{1, 0.5, 1}, 300, 0.5},
+		{"gray", [3]float64{0.5, 0.5, 0.5}, 0, 0},
+	} {
+		r, g, b := uint16(tc.rgb[0]*color.Max), uint16(tc.rgb[1]*color.Max), uint16(tc.rgb[2]*color.Max)
+		x := float64(color.Derive(color.ChannelHueX, r, g, b))
+		y := float64(color.Derive(color.ChannelHueY, r, g, b))
+		hue, strength := color.MeanHue(x, y)
+		if math.Abs(strength-tc.strength) > 1e-3 {
+			t.Errorf("%s: expected a strength of %v, got %v", tc.name, tc.strength, strength)
+		}
+		if tc.strength > 0 && math.Abs(hue-tc.hue) > 0.1 {
+			t.Errorf("%s: expected a hue of %v, got %v", tc.name, tc.hue, hue)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package color
+
+// Channel values are 16-bit and alpha-premultiplied, as returned by
+// image/color.Color.RGBA: from 0 to Max, whatever the depth of the source.
+// An 8-bit value v widens to v*0x101, so that 0xff maps to Max.
+const (
+	Max  = 0xffff
+	Max8 = 0xff
+)
+
+// Value is any of the channel types, or a raw 16-bit value.
+type Value interface {
+	~uint16 | ~uint32
+}
+
+// To8 narrows a 16-bit value to 8 bits, keeping its high byte.
+func To8[T Value](v T) uint8 {
+	return uint8(uint32(v) >> 8)
+}
+
+// From8 widens an 8-bit value to 16 bits.
+func From8(v uint8) uint16 {
+	return uint16(v) * 0x101
+}
+
+// Normalize maps a 16-bit value to [0, 1].
+func Normalize[T Value](v T) float64 {
+	return float64(v) / Max
+}
+
+// Invert complements a premultiplied value against its alpha, which is
+// Max - v for opaque pixels.
+func Invert[T Value](v, alpha T) T {
+	return alpha - min(v, alpha)
+}
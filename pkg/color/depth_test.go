@@ -0,0 +1,72 @@
+package color_test
+
+import (
+	"testing"
+
+	"github.com/ramadoka/penguin-logic/pkg/color"
+)
+
+func TestDepth(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		v16  uint32
+		v8   uint8
+	}{
+		{"zero", 0, 0},
+		{"max", color.Max, color.Max8},
+		{"max8 as 16-bit", color.Max8, 0},
+		{"just under max", color.Max - 1, color.Max8},
+		{"half", 0x8000, 0x80},
+	} {
+		if got := color.To8(tc.v16); got != tc.v8 {
+			t.Errorf("%s: expected To8(%#x) = %#x, got %#x", tc.name, tc.v16, tc.v8, got)
+		}
+	}
+	for _, tc := range []struct {
+		v8  uint8
+		v16 uint16
+	}{
+		{0, 0},
+		{color.Max8, color.Max},
+		{0x80, 0x8080},
+	} {
+		if got := color.From8(tc.v8); got != tc.v16 {
+			t.Errorf("expected From8(%#x) = %#x, got %#x", tc.v8, tc.v16, got)
+		}
+	}
+	for v := range color.Max8 + 1 {
+		if got := color.To8(color.From8(uint8(v))); got != uint8(v) {
+			t.Errorf("expected %#x back through From8 and To8, got %#x", v, got)
+		}
+	}
+	if got := color.Normalize(uint16(color.Max)); got != 1 {
+		t.Errorf("expected Max to normalize to 1, got %v", got)
+	}
+}
+
+func TestInvert(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		v, alpha uint32
+		want     uint32
+	}{
+		{"opaque black", 0, color.Max, color.Max},
+		{"opaque white", color.Max, color.Max, 0},
+		{"opaque mid", 0x4000, color.Max, color.Max - 0x4000},
+		{"transparent", 0, 0, 0},
+		{"half alpha black", 0, 0x8000, 0x8000},
+		{"half alpha white", 0x8000, 0x8000, 0},
+		{"half alpha mid", 0x3000, 0x8000, 0x5000},
+		// a value over its alpha is not premultiplied, and clamps to 0
+		{"over alpha", 0x9000, 0x8000, 0},
+	} {
+		if got := color.Invert(tc.v, tc.alpha); got != tc.want {
+			t.Errorf("%s: expected Invert(%#x, %#x) = %#x, got %#x", tc.name, tc.v, tc.alpha, tc.want, got)
+		}
+		if tc.v <= tc.alpha {
+			if back := color.Invert(color.Invert(tc.v, tc.alpha), tc.alpha); back != tc.v {
+				t.Errorf("%s: expected inverting twice to give %#x back, got %#x", tc.name, tc.v, back)
+			}
+		}
+	}
+}
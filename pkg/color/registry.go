@@ -0,0 +1,106 @@
+package color
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Func computes a channel from the 16-bit premultiplied red, green, blue and
+// alpha of a pixel, on the same 16-bit depth. Signed quantities are offset
+// to fit, as (r - b + Max) / 2 for red minus blue.
+type Func func(r, g, b, a uint16) uint16
+
+// ErrUnknownChannel is returned, wrapped, for channels that were never
+// registered.
+var ErrUnknownChannel = errors.New("unknown channel")
+
+type entry struct {
+	name string
+	fn   Func
+}
+
+// registry holds every channel, indexed by its value: the built-in ones
+// first, in declaration order, then the ones added by Register.
+var registry = struct {
+	mu      sync.RWMutex
+	entries []entry
+	names   map[string]Channel
+}{names: map[string]Channel{}}
+
+func init() {
+	builtins := []entry{
+		{"red", func(r, _, _, _ uint16) uint16 { return r }},
+		{"green", func(_, g, _, _ uint16) uint16 { return g }},
+		{"blue", func(_, _, b, _ uint16) uint16 { return b }},
+		{"gray", func(r, g, b, _ uint16) uint16 { return uint16((uint32(r) + uint32(g) + uint32(b)) / 3) }},
+		{"hue", nil},
+		{"saturation", nil},
+		{"value", nil},
+		{"hsl-saturation", nil},
+		{"lightness", nil},
+		{"lab-l", nil},
+		{"lab-a", nil},
+		{"lab-b", nil},
+		{"hue-x", nil},
+		{"hue-y", nil},
+	}
+	for idx, builtin := range builtins {
+		if builtin.fn == nil {
+			channel := Channel(idx)
+			builtin.fn = func(r, g, b, _ uint16) uint16 { return Derive(channel, r, g, b) }
+		}
+		if _, err := Register(builtin.name, builtin.fn); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Register adds a channel computed by fn, which the image and its integral
+// build planes and tables for the first time the channel is used. Names are
+// unique.
+func Register(name string, fn Func) (Channel, error) {
+	if name == "" || fn == nil {
+		return 0, fmt.Errorf("channel %q: a name and a function are required", name)
+	}
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if _, exists := registry.names[name]; exists {
+		return 0, fmt.Errorf("channel %q is already registered", name)
+	}
+	channel := Channel(len(registry.entries))
+	registry.entries = append(registry.entries, entry{name, fn})
+	registry.names[name] = channel
+	return channel, nil
+}
+
+// Lookup returns the channel registered under name.
+func Lookup(name string) (Channel, error) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	channel, exists := registry.names[name]
+	if !exists {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownChannel, name)
+	}
+	return channel, nil
+}
+
+// Func returns the function computing c.
+func (c Channel) Func() (Func, error) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	if c < 0 || int(c) >= len(registry.entries) {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownChannel, int(c))
+	}
+	return registry.entries[c].fn, nil
+}
+
+// Name returns the name c was registered under, empty for unknown channels.
+func (c Channel) Name() string {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	if c < 0 || int(c) >= len(registry.entries) {
+		return ""
+	}
+	return registry.entries[c].name
+}
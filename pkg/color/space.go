@@ -0,0 +1,90 @@
+package color
+
+import "math"
+
+// HSV converts r, g, b in [0, 1] to a hue in degrees and a saturation and
+// value in [0, 1]. The hue of gray pixels is 0.
+func HSV(r, g, b float64) (float64, float64, float64) {
+	hi := math.Max(r, math.Max(g, b))
+	lo := math.Min(r, math.Min(g, b))
+	delta := hi - lo
+	s := 0.0
+	if hi > 0 {
+		s = delta / hi
+	}
+	return hue(r, g, b, hi, delta), s, hi
+}
+
+// HSL converts r, g, b in [0, 1] to a hue in degrees and a saturation and
+// lightness in [0, 1].
+func HSL(r, g, b float64) (float64, float64, float64) {
+	hi := math.Max(r, math.Max(g, b))
+	lo := math.Min(r, math.Min(g, b))
+	delta := hi - lo
+	l := (hi + lo) / 2
+	s := 0.0
+	if delta > 0 {
+		s = delta / (1 - math.Abs(2*l-1))
+	}
+	return hue(r, g, b, hi, delta), math.Min(s, 1), l
+}
+
+func hue(r, g, b, hi, delta float64) float64 {
+	if delta == 0 {
+		return 0
+	}
+	h := 0.0
+	switch hi {
+	case r:
+		h = math.Mod((g-b)/delta, 6)
+	case g:
+		h = (b-r)/delta + 2
+	default:
+		h = (r-g)/delta + 4
+	}
+	h *= 60
+	if h < 0 {
+		h += 360
+	}
+	// a hue a hair under 0 rounds to 360 once wrapped
+	if h >= 360 {
+		h -= 360
+	}
+	return h
+}
+
+// The D65 white point of sRGB, in CIE XYZ.
+const (
+	whiteX = 0.95047
+	whiteY = 1.0
+	whiteZ = 1.08883
+)
+
+// Lab converts sRGB r, g, b in [0, 1] to CIELAB under D65: a lightness L* in
+// [0, 100] and the a* (green to red) and b* (blue to yellow) axes, roughly
+// in [-128, 127].
+func Lab(r, g, b float64) (float64, float64, float64) {
+	r, g, b = linear(r), linear(g), linear(b)
+	x := (0.4124564*r + 0.3575761*g + 0.1804375*b) / whiteX
+	y := (0.2126729*r + 0.7151522*g + 0.0721750*b) / whiteY
+	z := (0.0193339*r + 0.1191920*g + 0.9503041*b) / whiteZ
+	fx, fy, fz := labF(x), labF(y), labF(z)
+	return 116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)
+}
+
+// linear undoes the sRGB gamma.
+func linear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func labF(t float64) float64 {
+	const epsilon = 216.0 / 24389
+	const kappa = 24389.0 / 27
+	if t > epsilon {
+		return math.Cbrt(t)
+	}
+	return (kappa*t + 16) / 116
+}
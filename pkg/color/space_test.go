@@ -0,0 +1,135 @@
+package color_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/ramadoka/penguin-logic/pkg/color"
+)
+
+func TestColorSpaces(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		convert func(r, g, b float64) (float64, float64, float64)
+		rgb     [3]float64
+		want    [3]float64
+	}{
+		{"hsv red", color.HSV, [3]float64{1, 0, 0}, [3]float64{0, 1, 1}},
+		{"hsv green", color.HSV, [3]float64{0, 1, 0}, [3]float64{120, 1, 1}},
+		{"hsv dark blue", color.HSV, [3]float64{0, 0, 0.5}, [3]float64{240, 1, 0.5}},
+		{"hsl red", color.HSL, [3]float64{1, 0, 0}, [3]float64{0, 1, 0.5}},
+		{"hsl gray", color.HSL, [3]float64{0.5, 0.5, 0.5}, [3]float64{0, 0, 0.5}},
+		{"hsl pale yellow", color.HSL, [3]float64{1, 1, 0.5}, [3]float64{60, 1, 0.75}},
+		{"lab white", color.Lab, [3]float64{1, 1, 1}, [3]float64{100, 0, 0}},
+		{"lab black", color.Lab, [3]float64{0, 0, 0}, [3]float64{0, 0, 0}},
+		{"lab red", color.Lab, [3]float64{1, 0, 0}, [3]float64{53.24, 80.09, 67.2}},
+		{"lab blue", color.Lab, [3]float64{0, 0, 1}, [3]float64{32.3, 79.19, -107.86}},
+	} {
+		x, y, z := tc.convert(tc.rgb[0], tc.rgb[1], tc.rgb[2])
+		for idx, got := range []float64{x, y, z} {
+			if math.Abs(got-tc.want[idx]) > 0.01 {
+				t.Errorf("%s: expected %v, got %v", tc.name, tc.want, []float64{x, y, z})
+				break
+			}
+		}
+	}
+}
+
+func TestHueWraparound(t *testing.T) {
+	for _, tc := range []struct {
+		rgb  [3]float64
+		want float64
+	}{
+		{[3]float64{1, 0.01, 0}, 0.6},
+		{[3]float64{1, 0, 0.01}, 359.4},
+		{[3]float64{1, 0, 1e-18}, 0},
+		{[3]float64{1, 0, 1}, 300},
+		{[3]float64{1, 1, 0}, 60},
+	} {
+		for name, convert := range map[string]func(r, g, b float64) (float64, float64, float64){"hsv": color.HSV, "hsl": color.HSL} {
+			h, _, _ := convert(tc.rgb[0], tc.rgb[1], tc.rgb[2])
+			if h < 0 || h >= 360 || math.Abs(h-tc.want) > 1e-9 {
+				t.Errorf("%s %v: expected a hue of %v in [0, 360), got %v", name, tc.rgb, tc.want, h)
+			}
+		}
+	}
+}
+
+// hsvRGB and hslRGB are the textbook inverses of HSV and HSL.
+func hsvRGB(h, s, v float64) (float64, float64, float64) {
+	c := v * s
+	return chroma(h, c, v-c)
+}
+
+func hslRGB(h, s, l float64) (float64, float64, float64) {
+	c := (1 - math.Abs(2*l-1)) * s
+	return chroma(h, c, l-c/2)
+}
+
+func chroma(h, c, m float64) (float64, float64, float64) {
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	var r, g, b float64
+	switch int(h / 60) {
+	case 0:
+		r, g, b = c, x, 0
+	case 1:
+		r, g, b = x, c, 0
+	case 2:
+		r, g, b = 0, c, x
+	case 3:
+		r, g, b = 0, x, c
+	case 4:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	return r + m, g + m, b + m
+}
+
+// labRGB inverts Lab under the same D65 white point.
+func labRGB(l, a, bb float64) (float64, float64, float64) {
+	finv := func(f float64) float64 {
+		if f*f*f > 216.0/24389 {
+			return f * f * f
+		}
+		return (116*f - 16) / (24389.0 / 27)
+	}
+	fy := (l + 16) / 116
+	x := 0.95047 * finv(fy+a/500)
+	y := finv(fy)
+	z := 1.08883 * finv(fy-bb/200)
+	gamma := func(v float64) float64 {
+		if v <= 0.0031308 {
+			return 12.92 * v
+		}
+		return 1.055*math.Pow(v, 1/2.4) - 0.055
+	}
+	return gamma(3.2404542*x - 1.5371385*y - 0.4985314*z),
+		gamma(-0.9692660*x + 1.8760108*y + 0.0415560*z),
+		gamma(0.0556434*x - 0.2040259*y + 1.0572252*z)
+}
+
+func TestColorSpaceRoundTrips(t *testing.T) {
+	r := rand.New(rand.NewSource(5))
+	for range 1000 {
+		rgb := [3]float64{r.Float64(), r.Float64(), r.Float64()}
+		for _, tc := range []struct {
+			name    string
+			convert func(r, g, b float64) (float64, float64, float64)
+			back    func(x, y, z float64) (float64, float64, float64)
+			within  float64
+		}{
+			{"hsv", color.HSV, hsvRGB, 1e-9},
+			{"hsl", color.HSL, hslRGB, 1e-9},
+			// the matrices of Lab are only given to 7 digits
+			{"lab", color.Lab, labRGB, 1e-5},
+		} {
+			x, y, z := tc.convert(rgb[0], rgb[1], rgb[2])
+			br, bg, bb := tc.back(x, y, z)
+			if math.Abs(br-rgb[0]) > tc.within || math.Abs(bg-rgb[1]) > tc.within || math.Abs(bb-rgb[2]) > tc.within {
+				t.Fatalf("%s: expected %v back, got %v through %v", tc.name, rgb, []float64{br, bg, bb}, []float64{x, y, z})
+			}
+		}
+	}
+}
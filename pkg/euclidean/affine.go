@@ -0,0 +1,102 @@
+package euclidean
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrSingular is returned by Invert for a transform flattening the plane,
+// which cannot be undone.
+var ErrSingular = errors.New("singular transform")
+
+// Affine maps x, y to A*x + B*y + C, D*x + E*y + F.
+type Affine struct {
+	A, B, C float64
+	D, E, F float64
+}
+
+func Identity() Affine {
+	return Affine{A: 1, E: 1}
+}
+
+func Translation(v Vec) Affine {
+	return Affine{A: 1, C: v.X, E: 1, F: v.Y}
+}
+
+// Scaling multiplies the coordinates by sx and sy, about the origin.
+func Scaling(sx, sy float64) Affine {
+	return Affine{A: sx, E: sy}
+}
+
+// Rotation turns the plane by theta radians about the origin, clockwise on
+// screen since y points down.
+func Rotation(theta float64) Affine {
+	sin, cos := math.Sincos(theta)
+	return Affine{A: cos, B: -sin, D: sin, E: cos}
+}
+
+// Then is the transform applying t, then next.
+func (t Affine) Then(next Affine) Affine {
+	return Affine{
+		A: next.A*t.A + next.B*t.D,
+		B: next.A*t.B + next.B*t.E,
+		C: next.A*t.C + next.B*t.F + next.C,
+		D: next.D*t.A + next.E*t.D,
+		E: next.D*t.B + next.E*t.E,
+		F: next.D*t.C + next.E*t.F + next.F,
+	}
+}
+
+// Invert returns the transform undoing t.
+func (t Affine) Invert() (Affine, error) {
+	det := t.A*t.E - t.B*t.D
+	if det == 0 || math.IsNaN(det) || math.IsInf(det, 0) {
+		return Affine{}, fmt.Errorf("%w: determinant %g", ErrSingular, det)
+	}
+	a, b, d, e := t.E/det, -t.B/det, -t.D/det, t.A/det
+	return Affine{
+		A: a, B: b, C: -(a*t.C + b*t.F),
+		D: d, E: e, F: -(d*t.C + e*t.F),
+	}, nil
+}
+
+func (t Affine) Apply(p PointF) PointF {
+	return PointF{X: t.A*p.X + t.B*p.Y + t.C, Y: t.D*p.X + t.E*p.Y + t.F}
+}
+
+// ApplyVec maps a displacement, which the translation leaves unchanged.
+func (t Affine) ApplyVec(v Vec) Vec {
+	return Vec{X: t.A*v.X + t.B*v.Y, Y: t.D*v.X + t.E*v.Y}
+}
+
+// ApplyBound maps the corners of b and returns the smallest bound holding
+// them, its edges rounded outwards so that it covers every mapped pixel.
+func (t Affine) ApplyBound(b IBound) IBound {
+	corners := [4]PointF{
+		b.TopLeft().F(),
+		PF(float64(b.Right()), float64(b.Top())),
+		PF(float64(b.Left()), float64(b.Bottom())),
+		b.BottomRight().F(),
+	}
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, corner := range corners {
+		p := t.Apply(corner)
+		minX, maxX = min(minX, p.X), max(maxX, p.X)
+		minY, maxY = min(minY, p.Y), max(maxY, p.Y)
+	}
+	return Bound(
+		P2(X(outward(minX, math.Floor)), Y(outward(minY, math.Floor))),
+		P2(X(outward(maxX, math.Ceil)), Y(outward(maxY, math.Ceil))),
+	)
+}
+
+// outward rounds v with round, unless v is an integer but for the rounding
+// errors of the transform, which would add a pixel.
+func outward(v float64, round func(float64) float64) float64 {
+	if closest := math.Round(v); math.Abs(v-closest) < 1e-9 {
+		return closest
+	}
+	return round(v)
+}
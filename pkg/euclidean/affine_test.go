@@ -0,0 +1,82 @@
+package euclidean_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/ramadoka/penguin-logic/pkg/euclidean"
+)
+
+func near(a, b euclidean.PointF) bool {
+	return math.Abs(a.X-b.X) < 1e-9 && math.Abs(a.Y-b.Y) < 1e-9
+}
+
+func TestAffine(t *testing.T) {
+	p := euclidean.PF(3, -2)
+	for _, tc := range []struct {
+		name string
+		t    euclidean.Affine
+		want euclidean.PointF
+	}{
+		{"identity", euclidean.Identity(), euclidean.PF(3, -2)},
+		{"translation", euclidean.Translation(euclidean.V(1.5, 4)), euclidean.PF(4.5, 2)},
+		{"scaling", euclidean.Scaling(0.5, 3), euclidean.PF(1.5, -6)},
+		{"rotation", euclidean.Rotation(math.Pi / 2), euclidean.PF(2, 3)},
+		{"scaling then translation", euclidean.Scaling(2, 2).Then(euclidean.Translation(euclidean.V(1, 1))), euclidean.PF(7, -3)},
+		{"translation then scaling", euclidean.Translation(euclidean.V(1, 1)).Then(euclidean.Scaling(2, 2)), euclidean.PF(8, -2)},
+	} {
+		got := tc.t.Apply(p)
+		if !near(got, tc.want) {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.want.ToString(), got.ToString())
+		}
+		inverse, err := tc.t.Invert()
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if back := inverse.Apply(got); !near(back, p) {
+			t.Errorf("%s: expected the inverse to map back to %s, got %s", tc.name, p.ToString(), back.ToString())
+		}
+		want := tc.t.Apply(euclidean.PF(1, 0)).Sub(tc.t.Apply(euclidean.PF(0, 0)))
+		if v := tc.t.ApplyVec(euclidean.V(1, 0)); math.Abs(v.X-want.X) > 1e-9 || math.Abs(v.Y-want.Y) > 1e-9 {
+			t.Errorf("%s: expected ApplyVec to ignore the translation, got %s", tc.name, v.ToString())
+		}
+	}
+	if _, err := euclidean.Scaling(0, 1).Invert(); !errors.Is(err, euclidean.ErrSingular) {
+		t.Errorf("expected %v, got %v", euclidean.ErrSingular, err)
+	}
+}
+
+func TestAffineBound(t *testing.T) {
+	b := bound(10, 20, 30, 60)
+	if got := euclidean.Scaling(0.1, 0.1).ApplyBound(b); !same(got, bound(1, 2, 3, 6)) {
+		t.Errorf("expected exact corners to stay exact, got %s", got.ToString())
+	}
+	if got := euclidean.Scaling(0.25, 0.25).ApplyBound(b); !same(got, bound(2, 5, 8, 15)) {
+		t.Errorf("expected the edges rounded outwards, got %s", got.ToString())
+	}
+	if got := euclidean.Rotation(math.Pi / 2).ApplyBound(b); !same(got, bound(-60, 10, -20, 30)) {
+		t.Errorf("expected the rotated bound, got %s", got.ToString())
+	}
+	if got := b.CenterF(); got != euclidean.PF(20, 40) {
+		t.Errorf("expected the center (20, 40), got %s", got.ToString())
+	}
+	if got := bound(0, 0, 5, 3).CenterF(); got != euclidean.PF(2.5, 1.5) {
+		t.Errorf("expected the sub-pixel center (2.5, 1.5), got %s", got.ToString())
+	}
+}
+
+func TestPointScale(t *testing.T) {
+	center := euclidean.P2(10, 10)
+	// 3.5 pixels from center on both axes, which truncation made 3
+	p := euclidean.P2(17, 3)
+	if got := p.Scale(0.5, center); got != euclidean.P2(14, 7) {
+		t.Errorf("expected (14, 7), got %s", got.ToString())
+	}
+	if got := p.F().Round(); got != p {
+		t.Errorf("expected %s, got %s", p.ToString(), got.ToString())
+	}
+	if got := euclidean.PF(-0.5, 2.7).Floor(); got != euclidean.P2(-1, 2) {
+		t.Errorf("expected (-1, 2), got %s", got.ToString())
+	}
+}
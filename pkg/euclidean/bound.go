@@ -1,7 +1,9 @@
 package euclidean
 
 import (
+	"errors"
 	"fmt"
+	"math"
 )
 
 type bound struct {
@@ -9,8 +11,12 @@ type bound struct {
 	bottomRight Point
 }
 
+// IBound is a half-open rectangle, like image.Rectangle: it holds the points
+// from TopLeft, included, to BottomRight, excluded, which makes Width() by
+// Height() points. Bounds sharing an edge do not overlap.
 type IBound interface {
 	Contains(coord Point) bool
+	In(other IBound) bool
 	Width() W
 	Height() H
 	Top() Y
@@ -22,10 +28,25 @@ type IBound interface {
 	ToString() string
 	InnerCoords() []Point
 	Center() Point
+	CenterF() PointF
 	ShiftNeg(p Point) IBound
 	ShiftPos(p Point) IBound
+	Area() Area
+	Intersect(other IBound) (IBound, bool)
+	Union(other IBound) IBound
+	IoU(other IBound) float64
+	ScaleAround(center Point, factor float64) IBound
+	IsEmpty() bool
+	ClampTo(frame IBound) IBound
+	Pad(dw W, dh H) IBound
+	Inset(dw W, dh H) IBound
 }
 
+// ErrInvertedBound is returned, wrapped, by NewBound for a bottom right
+// corner above or left of the top left one.
+var ErrInvertedBound = errors.New("inverted bound")
+
+// Bound takes the corners as they are; an inverted bound is empty.
 func Bound(topLeft, bottomRight Point) IBound {
 	return &bound{
 		topLeft:     topLeft,
@@ -33,6 +54,24 @@ func Bound(topLeft, bottomRight Point) IBound {
 	}
 }
 
+// NewBound is Bound rejecting inverted corners.
+func NewBound(topLeft, bottomRight Point) (IBound, error) {
+	if bottomRight.X < topLeft.X || bottomRight.Y < topLeft.Y {
+		return nil, fmt.Errorf("%w: top left %s, bottom right %s", ErrInvertedBound, topLeft.ToString(), bottomRight.ToString())
+	}
+	return Bound(topLeft, bottomRight), nil
+}
+
+// Span is the bound having a and b as opposite corners, in any order.
+func Span(a, b Point) IBound {
+	return Bound(P2(min(a.X, b.X), min(a.Y, b.Y)), P2(max(a.X, b.X), max(a.Y, b.Y)))
+}
+
+// BoundAt is the bound of w x h points from topLeft.
+func BoundAt(topLeft Point, w W, h H) IBound {
+	return Bound(topLeft, P2(topLeft.X.Add(w), topLeft.Y.Add(h)))
+}
+
 func (b bound) ShiftNeg(p Point) IBound {
 	newTopLeft := b.topLeft.ShiftNeg(p)
 	newBottomRight := b.bottomRight.ShiftNeg(p)
@@ -51,10 +90,113 @@ func (b bound) Center() Point {
 	return P2(centerX, centerY)
 }
 
+// CenterF is the exact center of the bound, which Center rounds down to a
+// pixel.
+func (b bound) CenterF() PointF {
+	return PF(float64(b.Left()+b.Right())/2, float64(b.Top()+b.Bottom())/2)
+}
+
+// Area is the number of points of the bound, 0 when it is empty.
+func (b bound) Area() Area {
+	if b.IsEmpty() {
+		return 0
+	}
+	return b.Width().Mul(b.Height())
+}
+
+// IsEmpty tells whether the bound holds no point.
+func (b bound) IsEmpty() bool {
+	return b.Right() <= b.Left() || b.Bottom() <= b.Top()
+}
+
+// ClampTo moves every edge of the bound into frame. The result is the
+// overlap of both bounds, or an empty bound on the edge of frame when they
+// do not overlap.
+func (b bound) ClampTo(frame IBound) IBound {
+	clampX := func(x X) X { return min(max(x, frame.Left()), frame.Right()) }
+	clampY := func(y Y) Y { return min(max(y, frame.Top()), frame.Bottom()) }
+	return Bound(
+		P2(clampX(b.Left()), clampY(b.Top())),
+		P2(clampX(b.Right()), clampY(b.Bottom())),
+	)
+}
+
+// Pad grows the bound by dw on the left and on the right and by dh on the
+// top and at the bottom. Negative margins shrink it, as Inset does.
+func (b bound) Pad(dw W, dh H) IBound {
+	left, right := b.Left().Sub(dw), b.Right().Add(dw)
+	top, bottom := b.Top().Sub(dh), b.Bottom().Add(dh)
+	// an inset larger than the bound collapses it on its center
+	if right < left {
+		left = (b.Left() + b.Right()) / 2
+		right = left
+	}
+	if bottom < top {
+		top = (b.Top() + b.Bottom()) / 2
+		bottom = top
+	}
+	return Bound(P2(left, top), P2(right, bottom))
+}
+
+// Inset shrinks the bound by dw on the left and on the right and by dh on
+// the top and at the bottom, down to an empty bound on its center.
+func (b bound) Inset(dw W, dh H) IBound {
+	return b.Pad(-dw, -dh)
+}
+
+// Intersect returns the overlap of both bounds, or false when they do not
+// overlap.
+func (b bound) Intersect(other IBound) (IBound, bool) {
+	topLeft := P2(max(b.Left(), other.Left()), max(b.Top(), other.Top()))
+	bottomRight := P2(min(b.Right(), other.Right()), min(b.Bottom(), other.Bottom()))
+	if bottomRight.X <= topLeft.X || bottomRight.Y <= topLeft.Y {
+		return nil, false
+	}
+	return Bound(topLeft, bottomRight), true
+}
+
+// Union returns the smallest bound containing both bounds.
+func (b bound) Union(other IBound) IBound {
+	topLeft := P2(min(b.Left(), other.Left()), min(b.Top(), other.Top()))
+	bottomRight := P2(max(b.Right(), other.Right()), max(b.Bottom(), other.Bottom()))
+	return Bound(topLeft, bottomRight)
+}
+
+// IoU is the intersection over union of both areas, 0 for disjoint bounds.
+func (b bound) IoU(other IBound) float64 {
+	overlap, ok := b.Intersect(other)
+	if !ok {
+		return 0
+	}
+	inter := overlap.Area()
+	union := b.Area() + other.Area() - inter
+	if union <= 0 {
+		return 0
+	}
+	return float64(inter) / float64(union)
+}
+
+// ScaleAround multiplies the distance of both corners to center by factor,
+// rounding them to the closest pixel: a factor above 1 grows the bound
+// around center, one under 1 shrinks it and a negative one mirrors it.
+func (b bound) ScaleAround(center Point, factor float64) IBound {
+	scaleX := func(x X) X {
+		return center.X + X(math.Round(float64(x-center.X)*factor))
+	}
+	scaleY := func(y Y) Y {
+		return center.Y + Y(math.Round(float64(y-center.Y)*factor))
+	}
+	return Span(
+		P2(scaleX(b.Left()), scaleY(b.Top())),
+		P2(scaleX(b.Right()), scaleY(b.Bottom())),
+	)
+}
+
+// InnerCoords lists the points of the bound, row by row.
 func (b bound) InnerCoords() []Point {
 	accumulator := []Point{}
-	for y := b.Top() + 1; y < b.Bottom(); y++ {
-		for x := b.Left() + 1; x < b.Right(); x++ {
+	for y := b.Top(); y < b.Bottom(); y++ {
+		for x := b.Left(); x < b.Right(); x++ {
 			accumulator = append(accumulator, P2(x, y))
 		}
 	}
@@ -101,6 +243,12 @@ func (b bound) Bottom() Y {
 	return b.bottomRight.Y
 }
 
+// In tells whether every point of b lies in other.
+func (b bound) In(other IBound) bool {
+	return b.Left() >= other.Left() && b.Right() <= other.Right() &&
+		b.Top() >= other.Top() && b.Bottom() <= other.Bottom()
+}
+
 func (b bound) Contains(coord Point) bool {
 	if coord.X < b.topLeft.X {
 		return false
@@ -108,10 +256,10 @@ func (b bound) Contains(coord Point) bool {
 	if coord.Y < b.topLeft.Y {
 		return false
 	}
-	if coord.X > b.bottomRight.X {
+	if coord.X >= b.bottomRight.X {
 		return false
 	}
-	if coord.Y > b.bottomRight.Y {
+	if coord.Y >= b.bottomRight.Y {
 		return false
 	}
 	return true
@@ -0,0 +1,62 @@
+package euclidean_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ramadoka/penguin-logic/pkg/euclidean"
+)
+
+func bound(left, top, right, bottom int) euclidean.IBound {
+	return euclidean.Bound(euclidean.P2(euclidean.X(left), euclidean.Y(top)), euclidean.P2(euclidean.X(right), euclidean.Y(bottom)))
+}
+
+func same(a, b euclidean.IBound) bool {
+	return a.TopLeft() == b.TopLeft() && a.BottomRight() == b.BottomRight()
+}
+
+func TestBoundConstructors(t *testing.T) {
+	if _, err := euclidean.NewBound(euclidean.P2(4, 0), euclidean.P2(2, 3)); !errors.Is(err, euclidean.ErrInvertedBound) {
+		t.Errorf("expected %v, got %v", euclidean.ErrInvertedBound, err)
+	}
+	if b, err := euclidean.NewBound(euclidean.P2(2, 0), euclidean.P2(2, 3)); err != nil || !b.IsEmpty() {
+		t.Errorf("expected an empty bound, got %v (%v)", b, err)
+	}
+	if b := euclidean.Span(euclidean.P2(4, 0), euclidean.P2(2, 3)); !same(b, bound(2, 0, 4, 3)) {
+		t.Errorf("expected the corners in order, got %s", b.ToString())
+	}
+	if b := euclidean.BoundAt(euclidean.P2(1, 2), 3, 4); !same(b, bound(1, 2, 4, 6)) || b.Area() != 12 {
+		t.Errorf("expected 3 x 4 points from (1, 2), got %s", b.ToString())
+	}
+	if inverted := bound(4, 4, 2, 2); !inverted.IsEmpty() || inverted.Area() != 0 {
+		t.Errorf("expected an inverted bound to be empty, got an area of %d", inverted.Area())
+	}
+}
+
+func TestBoundGeometry(t *testing.T) {
+	frame := bound(0, 0, 10, 8)
+	for _, tc := range []struct {
+		name      string
+		got, want euclidean.IBound
+	}{
+		{"clamp inside", bound(2, 2, 5, 5).ClampTo(frame), bound(2, 2, 5, 5)},
+		{"clamp across", bound(-3, 6, 4, 12).ClampTo(frame), bound(0, 6, 4, 8)},
+		{"clamp outside", bound(12, 2, 15, 5).ClampTo(frame), bound(10, 2, 10, 5)},
+		{"pad", bound(2, 2, 5, 5).Pad(1, 2), bound(1, 0, 6, 7)},
+		{"inset", bound(2, 2, 8, 6).Inset(1, 1), bound(3, 3, 7, 5)},
+		{"inset past the center", bound(2, 2, 8, 6).Inset(4, 1), bound(5, 3, 5, 5)},
+		{"scale around the center", bound(2, 2, 6, 6).ScaleAround(euclidean.P2(4, 4), 1.5), bound(1, 1, 7, 7)},
+		{"scale around a corner", bound(2, 2, 6, 6).ScaleAround(euclidean.P2(2, 2), 0.5), bound(2, 2, 4, 4)},
+		{"mirror", bound(2, 2, 6, 6).ScaleAround(euclidean.P2(0, 0), -1), bound(-6, -6, -2, -2)},
+	} {
+		if !same(tc.got, tc.want) {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.want.ToString(), tc.got.ToString())
+		}
+	}
+	if !bound(3, 3, 5, 5).ClampTo(bound(4, 0, 10, 4)).In(bound(4, 0, 10, 4)) {
+		t.Error("expected a clamped bound to lie in its frame")
+	}
+	if b := bound(12, 2, 15, 5).ClampTo(frame); !b.IsEmpty() {
+		t.Errorf("expected a bound outside of the frame to clamp empty, got %s", b.ToString())
+	}
+}
@@ -0,0 +1,79 @@
+package euclidean
+
+import (
+	"fmt"
+	"math"
+)
+
+// PointF is a point with sub-pixel coordinates. The pixel at Point p covers
+// PointF p to p + (1, 1), its center being p + (0.5, 0.5).
+type PointF struct {
+	X float64
+	Y float64
+}
+
+// Vec is the displacement between two PointF.
+type Vec struct {
+	X float64
+	Y float64
+}
+
+func PF(x, y float64) PointF {
+	return PointF{X: x, Y: y}
+}
+
+func V(x, y float64) Vec {
+	return Vec{X: x, Y: y}
+}
+
+// F converts p to floating point, exactly.
+func (p Point) F() PointF {
+	return PointF{X: float64(p.X), Y: float64(p.Y)}
+}
+
+// Round returns the closest Point, halves rounded away from zero.
+func (p PointF) Round() Point {
+	return P2(X(math.Round(p.X)), Y(math.Round(p.Y)))
+}
+
+// Floor returns the Point of the pixel p lies in.
+func (p PointF) Floor() Point {
+	return P2(X(math.Floor(p.X)), Y(math.Floor(p.Y)))
+}
+
+func (p PointF) Add(v Vec) PointF {
+	return PointF{X: p.X + v.X, Y: p.Y + v.Y}
+}
+
+// Sub is the vector from other to p.
+func (p PointF) Sub(other PointF) Vec {
+	return Vec{X: p.X - other.X, Y: p.Y - other.Y}
+}
+
+func (p PointF) ToString() string {
+	return fmt.Sprintf("(%g, %g)", p.X, p.Y)
+}
+
+func (v Vec) Add(other Vec) Vec {
+	return Vec{X: v.X + other.X, Y: v.Y + other.Y}
+}
+
+func (v Vec) Sub(other Vec) Vec {
+	return Vec{X: v.X - other.X, Y: v.Y - other.Y}
+}
+
+func (v Vec) Scale(factor float64) Vec {
+	return Vec{X: v.X * factor, Y: v.Y * factor}
+}
+
+func (v Vec) Dot(other Vec) float64 {
+	return v.X*other.X + v.Y*other.Y
+}
+
+func (v Vec) Len() float64 {
+	return math.Hypot(v.X, v.Y)
+}
+
+func (v Vec) ToString() string {
+	return fmt.Sprintf("<%g, %g>", v.X, v.Y)
+}
@@ -0,0 +1,220 @@
+package euclidean
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrTooFewPoints is returned, wrapped, by EstimateHomography for less than
+// four correspondences.
+var ErrTooFewPoints = errors.New("too few correspondences")
+
+// Homography is a perspective transform in homogeneous coordinates: x, y
+// maps to the first two rows applied to (x, y, 1), divided by the third.
+// It maps the sub-pixel coordinates of PointF, the top left corner of the
+// pixel at Point p being p itself.
+type Homography [3][3]float64
+
+// Homography returns the perspective transform doing what t does.
+func (t Affine) Homography() Homography {
+	return Homography{
+		{t.A, t.B, t.C},
+		{t.D, t.E, t.F},
+		{0, 0, 1},
+	}
+}
+
+// Then is the transform applying h, then next.
+func (h Homography) Then(next Homography) Homography {
+	var out Homography
+	for r := range 3 {
+		for c := range 3 {
+			for k := range 3 {
+				out[r][c] += next[r][k] * h[k][c]
+			}
+		}
+	}
+	return out
+}
+
+func (h Homography) cofactor(r, c int) float64 {
+	r0, r1 := (r+1)%3, (r+2)%3
+	c0, c1 := (c+1)%3, (c+2)%3
+	return h[r0][c0]*h[r1][c1] - h[r0][c1]*h[r1][c0]
+}
+
+func (h Homography) det() float64 {
+	return h[0][0]*h.cofactor(0, 0) + h[0][1]*h.cofactor(0, 1) + h[0][2]*h.cofactor(0, 2)
+}
+
+// Invert returns the transform undoing h.
+func (h Homography) Invert() (Homography, error) {
+	det := h.det()
+	if det == 0 || math.IsNaN(det) || math.IsInf(det, 0) {
+		return Homography{}, fmt.Errorf("%w: determinant %g", ErrSingular, det)
+	}
+	var out Homography
+	for r := range 3 {
+		for c := range 3 {
+			// the inverse is the transposed cofactor matrix over det
+			out[r][c] = h.cofactor(c, r) / det
+		}
+	}
+	return out, nil
+}
+
+// Apply maps p. A point sent to infinity, on the horizon of h, has
+// infinite or NaN coordinates.
+func (h Homography) Apply(p PointF) PointF {
+	w := h[2][0]*p.X + h[2][1]*p.Y + h[2][2]
+	return PointF{
+		X: (h[0][0]*p.X + h[0][1]*p.Y + h[0][2]) / w,
+		Y: (h[1][0]*p.X + h[1][1]*p.Y + h[1][2]) / w,
+	}
+}
+
+// EstimateHomography returns the homography mapping every point of from to
+// the point of to at the same index: exactly for four correspondences, and
+// in the least squares sense for more. Three of the points being aligned
+// leave it undetermined, which fails with ErrSingular.
+func EstimateHomography(from, to []Point) (Homography, error) {
+	if len(from) != len(to) {
+		return Homography{}, fmt.Errorf("%d points to map on %d", len(from), len(to))
+	}
+	if len(from) < 4 {
+		return Homography{}, fmt.Errorf("%w: %d, expected at least 4", ErrTooFewPoints, len(from))
+	}
+	// Both sets are moved around the origin at a mean distance of sqrt(2),
+	// which keeps the equations well conditioned for any image size.
+	src, normFrom, err := normalize(from)
+	if err != nil {
+		return Homography{}, err
+	}
+	dst, normTo, err := normalize(to)
+	if err != nil {
+		return Homography{}, err
+	}
+	// Every correspondence gives two linear equations on the first eight
+	// entries, the last one being 1; they are solved through the normal
+	// equations.
+	var ata [8][8]float64
+	var atb [8]float64
+	for idx := range src {
+		x, y, u, v := src[idx].X, src[idx].Y, dst[idx].X, dst[idx].Y
+		for _, eq := range [2]struct {
+			row [8]float64
+			rhs float64
+		}{
+			{[8]float64{x, y, 1, 0, 0, 0, -u * x, -u * y}, u},
+			{[8]float64{0, 0, 0, x, y, 1, -v * x, -v * y}, v},
+		} {
+			for r := range 8 {
+				for c := range 8 {
+					ata[r][c] += eq.row[r] * eq.row[c]
+				}
+				atb[r] += eq.row[r] * eq.rhs
+			}
+		}
+	}
+	entries, err := solve(ata, atb)
+	if err != nil {
+		return Homography{}, err
+	}
+	normalized := Homography{
+		{entries[0], entries[1], entries[2]},
+		{entries[3], entries[4], entries[5]},
+		{entries[6], entries[7], 1},
+	}
+	// Aligned points fit a transform flattening the plane on a line, which
+	// shows as a vanishing determinant on the normalized coordinates.
+	if math.Abs(normalized.det()) < 1e-6 {
+		return Homography{}, fmt.Errorf("%w: degenerate correspondences", ErrSingular)
+	}
+	denormalize, err := normTo.Invert()
+	if err != nil {
+		return Homography{}, err
+	}
+	h := normFrom.Homography().Then(normalized).Then(denormalize.Homography())
+	return h.scaled(), nil
+}
+
+// scaled divides h by its last entry, when it can, so that equal
+// transforms compare equal.
+func (h Homography) scaled() Homography {
+	if h[2][2] == 0 {
+		return h
+	}
+	s := h[2][2]
+	for r := range 3 {
+		for c := range 3 {
+			h[r][c] /= s
+		}
+	}
+	return h
+}
+
+// normalize returns points moved so that their centroid is the origin and
+// their mean distance to it sqrt(2), with the transform doing it.
+func normalize(points []Point) ([]PointF, Affine, error) {
+	var centroid Vec
+	for _, p := range points {
+		centroid = centroid.Add(V(float64(p.X), float64(p.Y)))
+	}
+	centroid = centroid.Scale(1 / float64(len(points)))
+	spread := 0.0
+	for _, p := range points {
+		spread += p.F().Sub(PF(centroid.X, centroid.Y)).Len() / float64(len(points))
+	}
+	if spread == 0 {
+		return nil, Affine{}, fmt.Errorf("%w: every point is the same", ErrSingular)
+	}
+	s := math.Sqrt2 / spread
+	t := Translation(centroid.Scale(-1)).Then(Scaling(s, s))
+	out := make([]PointF, len(points))
+	for idx, p := range points {
+		out[idx] = t.Apply(p.F())
+	}
+	return out, t, nil
+}
+
+// solve solves the linear system a x = b by Gaussian elimination with
+// partial pivoting.
+func solve(a [8][8]float64, b [8]float64) ([8]float64, error) {
+	const n = 8
+	scale := 0.0
+	for r := range n {
+		for c := range n {
+			scale = max(scale, math.Abs(a[r][c]))
+		}
+	}
+	for col := range n {
+		pivot := col
+		for r := col + 1; r < n; r++ {
+			if math.Abs(a[r][col]) > math.Abs(a[pivot][col]) {
+				pivot = r
+			}
+		}
+		if math.Abs(a[pivot][col]) <= 1e-12*scale {
+			return [n]float64{}, fmt.Errorf("%w: degenerate correspondences", ErrSingular)
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		b[col], b[pivot] = b[pivot], b[col]
+		for r := col + 1; r < n; r++ {
+			f := a[r][col] / a[col][col]
+			for c := col; c < n; c++ {
+				a[r][c] -= f * a[col][c]
+			}
+			b[r] -= f * b[col]
+		}
+	}
+	var x [n]float64
+	for r := n - 1; r >= 0; r-- {
+		v := b[r]
+		for c := r + 1; c < n; c++ {
+			v -= a[r][c] * x[c]
+		}
+		x[r] = v / a[r][r]
+	}
+	return x, nil
+}
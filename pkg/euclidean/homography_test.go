@@ -0,0 +1,79 @@
+package euclidean_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/ramadoka/penguin-logic/pkg/euclidean"
+)
+
+func TestEstimateHomography(t *testing.T) {
+	want := euclidean.Homography{
+		{0.9, 0.12, 14},
+		{-0.05, 1.1, 7},
+		{0.0004, -0.0002, 1},
+	}
+	from := []euclidean.Point{
+		euclidean.P2(0, 0), euclidean.P2(640, 0), euclidean.P2(640, 480), euclidean.P2(0, 480),
+		euclidean.P2(320, 240), euclidean.P2(100, 400),
+	}
+	for _, n := range []int{4, len(from)} {
+		// the targets are rounded to points, so the estimate is only close
+		to := make([]euclidean.Point, n)
+		for idx := range to {
+			to[idx] = want.Apply(from[idx].F()).Round()
+		}
+		got, err := euclidean.EstimateHomography(from[:n], to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range []euclidean.PointF{euclidean.PF(10, 20), euclidean.PF(600, 50), euclidean.PF(320, 470)} {
+			if d := got.Apply(p).Sub(want.Apply(p)).Len(); d > 1 {
+				t.Errorf("%d points: %s maps %g pixels away", n, p.ToString(), d)
+			}
+		}
+		inverse, err := got.Invert()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if back := inverse.Apply(got.Apply(euclidean.PF(33, 44))); !near(back, euclidean.PF(33, 44)) {
+			t.Errorf("%d points: expected the inverse to map back to (33, 44), got %s", n, back.ToString())
+		}
+	}
+
+	square := []euclidean.Point{euclidean.P2(0, 0), euclidean.P2(10, 0), euclidean.P2(10, 10), euclidean.P2(0, 10)}
+	shifted := []euclidean.Point{euclidean.P2(5, 3), euclidean.P2(15, 3), euclidean.P2(15, 13), euclidean.P2(5, 13)}
+	got, err := euclidean.EstimateHomography(square, shifted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	affine := euclidean.Translation(euclidean.V(5, 3)).Homography()
+	for r := range 3 {
+		for c := range 3 {
+			if math.Abs(got[r][c]-affine[r][c]) > 1e-9 {
+				t.Fatalf("expected the translation %v, got %v", affine, got)
+			}
+		}
+	}
+
+	if _, err := euclidean.EstimateHomography(square[:3], shifted[:3]); !errors.Is(err, euclidean.ErrTooFewPoints) {
+		t.Errorf("expected %v, got %v", euclidean.ErrTooFewPoints, err)
+	}
+	aligned := []euclidean.Point{euclidean.P2(0, 0), euclidean.P2(5, 5), euclidean.P2(10, 10), euclidean.P2(0, 10)}
+	if _, err := euclidean.EstimateHomography(aligned, shifted); !errors.Is(err, euclidean.ErrSingular) {
+		t.Errorf("expected %v on aligned points, got %v", euclidean.ErrSingular, err)
+	}
+}
+
+func TestHomographyOfAffine(t *testing.T) {
+	a := euclidean.Rotation(0.3).Then(euclidean.Scaling(2, 0.5)).Then(euclidean.Translation(euclidean.V(-4, 9)))
+	b := euclidean.Scaling(3, 3)
+	p := euclidean.PF(7, -1)
+	if got, want := a.Homography().Apply(p), a.Apply(p); !near(got, want) {
+		t.Errorf("expected %s, got %s", want.ToString(), got.ToString())
+	}
+	if got, want := a.Homography().Then(b.Homography()).Apply(p), a.Then(b).Apply(p); !near(got, want) {
+		t.Errorf("expected composing to agree, %s against %s", got.ToString(), want.ToString())
+	}
+}
@@ -72,11 +72,10 @@ func (p Point) ToIndex(topLeft Point, width W) int {
 	return int(int(yDiff)*int(width) + int(xDiff))
 }
 
+// Scale multiplies the distance of p to center by factor and rounds the
+// result to the closest point once.
 func (p Point) Scale(factor float64, center Point) Point {
-	return Point{
-		X: X(float64(p.X-center.X)*factor) + center.X,
-		Y: Y(float64(p.Y-center.Y)*factor) + center.Y,
-	}
+	return center.F().Add(p.F().Sub(center.F()).Scale(factor)).Round()
 }
 
 func FromIndex(width W, height H, index int) Point {
@@ -0,0 +1,160 @@
+package euclidean
+
+import "math"
+
+// Shape is a region of the plane in the sub-pixel coordinates of PointF. A
+// pixel belongs to a shape when its center does, which is how Rasterize
+// turns a shape into a Mask.
+type Shape interface {
+	Contains(p PointF) bool
+	// Bound is the smallest bound holding every pixel of the shape.
+	Bound() IBound
+	Area() float64
+}
+
+// Circle holds the points at most Radius away from Center.
+type Circle struct {
+	Center PointF
+	Radius float64
+}
+
+// Annulus holds the points farther than Inner from Center, and at most
+// Outer away from it: annuli sharing a radius do not overlap, and one of
+// Inner 0 is a Circle without its center.
+type Annulus struct {
+	Center PointF
+	Inner  float64
+	Outer  float64
+}
+
+// Polygon is a simple polygon, its vertices listed in either order and the
+// last one joined to the first. Contains follows the even-odd rule.
+type Polygon []PointF
+
+func (c Circle) Contains(p PointF) bool {
+	return p.Sub(c.Center).Len() <= c.Radius
+}
+
+func (c Circle) Bound() IBound {
+	return pixelBound(c.Center.X-c.Radius, c.Center.Y-c.Radius, c.Center.X+c.Radius, c.Center.Y+c.Radius)
+}
+
+func (c Circle) Area() float64 {
+	return math.Pi * c.Radius * c.Radius
+}
+
+func (a Annulus) Contains(p PointF) bool {
+	d := p.Sub(a.Center).Len()
+	return d > a.Inner && d <= a.Outer
+}
+
+func (a Annulus) Bound() IBound {
+	return Circle{Center: a.Center, Radius: a.Outer}.Bound()
+}
+
+func (a Annulus) Area() float64 {
+	inner := max(a.Inner, 0)
+	if a.Outer <= inner {
+		return 0
+	}
+	return math.Pi * (a.Outer*a.Outer - inner*inner)
+}
+
+// Contains casts a ray from p to the right and counts the edges it crosses.
+func (poly Polygon) Contains(p PointF) bool {
+	in := false
+	for idx, a := range poly {
+		b := poly[(idx+1)%len(poly)]
+		if (a.Y > p.Y) == (b.Y > p.Y) {
+			continue
+		}
+		if x := a.X + (p.Y-a.Y)*(b.X-a.X)/(b.Y-a.Y); p.X < x {
+			in = !in
+		}
+	}
+	return in
+}
+
+func (poly Polygon) Bound() IBound {
+	if len(poly) == 0 {
+		return Bound(P2(0, 0), P2(0, 0))
+	}
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range poly {
+		minX, maxX = min(minX, p.X), max(maxX, p.X)
+		minY, maxY = min(minY, p.Y), max(maxY, p.Y)
+	}
+	return pixelBound(minX, minY, maxX, maxY)
+}
+
+// Area is given by the shoelace formula.
+func (poly Polygon) Area() float64 {
+	twice := 0.0
+	for idx, a := range poly {
+		b := poly[(idx+1)%len(poly)]
+		twice += a.X*b.Y - b.X*a.Y
+	}
+	return math.Abs(twice) / 2
+}
+
+// pixelBound is the bound of the pixels whose center lies in the given
+// extent, which may be empty.
+func pixelBound(minX, minY, maxX, maxY float64) IBound {
+	left, top := X(math.Ceil(minX-0.5)), Y(math.Ceil(minY-0.5))
+	right, bottom := X(math.Floor(maxX-0.5))+1, Y(math.Floor(maxY-0.5))+1
+	return Bound(P2(left, top), P2(max(right, left), max(bottom, top)))
+}
+
+// Mask is the set of pixels of a shape within its bound.
+type Mask struct {
+	bound IBound
+	in    []bool
+}
+
+// Rasterize tests the center of every pixel of the bound of s.
+func Rasterize(s Shape) Mask {
+	b := s.Bound()
+	m := Mask{bound: b, in: make([]bool, int(b.Area()))}
+	for idx, p := range b.InnerCoords() {
+		m.in[idx] = s.Contains(PF(float64(p.X)+0.5, float64(p.Y)+0.5))
+	}
+	return m
+}
+
+func (m Mask) Bound() IBound {
+	return m.bound
+}
+
+func (m Mask) Contains(p Point) bool {
+	if m.bound == nil || !m.bound.Contains(p) {
+		return false
+	}
+	return m.in[p.ToIndex(m.bound.TopLeft(), m.bound.Width())]
+}
+
+// Count is the number of pixels of the mask, close to the area of the
+// shape for shapes much larger than a pixel.
+func (m Mask) Count() int {
+	n := 0
+	for _, in := range m.in {
+		if in {
+			n++
+		}
+	}
+	return n
+}
+
+// Points lists the pixels of the mask, row by row.
+func (m Mask) Points() []Point {
+	if m.bound == nil {
+		return nil
+	}
+	points := make([]Point, 0, m.Count())
+	for idx, p := range m.bound.InnerCoords() {
+		if m.in[idx] {
+			points = append(points, p)
+		}
+	}
+	return points
+}
@@ -0,0 +1,73 @@
+package euclidean_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ramadoka/penguin-logic/pkg/euclidean"
+)
+
+func TestShapes(t *testing.T) {
+	disk := euclidean.Circle{Center: euclidean.PF(50, 40), Radius: 20}
+	ring := euclidean.Annulus{Center: euclidean.PF(50, 40), Inner: 15, Outer: 20}
+	triangle := euclidean.Polygon{euclidean.PF(0, 0), euclidean.PF(40, 0), euclidean.PF(0, 30)}
+	for _, tc := range []struct {
+		name  string
+		shape euclidean.Shape
+		bound euclidean.IBound
+		area  float64
+		in    []euclidean.PointF
+		out   []euclidean.PointF
+	}{
+		{"circle", disk, bound(30, 20, 70, 60), math.Pi * 400,
+			[]euclidean.PointF{euclidean.PF(50, 40), euclidean.PF(69.5, 40), euclidean.PF(50, 20)},
+			[]euclidean.PointF{euclidean.PF(30.5, 20.5), euclidean.PF(71, 40)}},
+		{"annulus", ring, bound(30, 20, 70, 60), math.Pi * (400 - 225),
+			[]euclidean.PointF{euclidean.PF(67, 40), euclidean.PF(50, 22)},
+			[]euclidean.PointF{euclidean.PF(50, 40), euclidean.PF(60, 40), euclidean.PF(50, 25)}},
+		{"triangle", triangle, bound(0, 0, 40, 30), 600,
+			[]euclidean.PointF{euclidean.PF(1, 1), euclidean.PF(19, 14)},
+			[]euclidean.PointF{euclidean.PF(21, 15), euclidean.PF(-1, 5), euclidean.PF(39, 29)}},
+	} {
+		if got := tc.shape.Bound(); !same(got, tc.bound) {
+			t.Errorf("%s: expected the bound %s, got %s", tc.name, tc.bound.ToString(), got.ToString())
+		}
+		if got := tc.shape.Area(); math.Abs(got-tc.area) > 1e-9 {
+			t.Errorf("%s: expected an area of %g, got %g", tc.name, tc.area, got)
+		}
+		for _, p := range tc.in {
+			if !tc.shape.Contains(p) {
+				t.Errorf("%s: expected %s inside", tc.name, p.ToString())
+			}
+		}
+		for _, p := range tc.out {
+			if tc.shape.Contains(p) {
+				t.Errorf("%s: expected %s outside", tc.name, p.ToString())
+			}
+		}
+		mask := euclidean.Rasterize(tc.shape)
+		if n := float64(mask.Count()); math.Abs(n-tc.area) > 0.05*tc.area {
+			t.Errorf("%s: expected about %g pixels, got %g", tc.name, tc.area, n)
+		}
+		for _, p := range mask.Points() {
+			if !mask.Contains(p) || !tc.shape.Contains(euclidean.PF(float64(p.X)+0.5, float64(p.Y)+0.5)) {
+				t.Fatalf("%s: expected the center of %s inside", tc.name, p.ToString())
+			}
+		}
+	}
+
+	if mask := euclidean.Rasterize(euclidean.Circle{Center: euclidean.PF(3, 3), Radius: -1}); mask.Count() != 0 || !mask.Bound().IsEmpty() {
+		t.Errorf("expected an empty mask for a negative radius, got %d pixels", mask.Count())
+	}
+	if (euclidean.Mask{}).Contains(euclidean.P2(0, 0)) || len((euclidean.Mask{}).Points()) != 0 {
+		t.Error("expected the zero mask to be empty")
+	}
+	if mask := euclidean.Rasterize(euclidean.Circle{Center: euclidean.PF(0.5, 0.5), Radius: 0.1}); mask.Count() != 1 || !mask.Contains(euclidean.P2(0, 0)) {
+		t.Errorf("expected a circle around the center of a pixel to hold it, got %d pixels", mask.Count())
+	}
+	// rings sharing a radius split the disk
+	inner := euclidean.Rasterize(euclidean.Circle{Center: euclidean.PF(50, 40), Radius: 15})
+	if inner.Count()+euclidean.Rasterize(ring).Count() != euclidean.Rasterize(disk).Count() {
+		t.Error("expected the disk to be its inner circle and its ring")
+	}
+}
@@ -0,0 +1,135 @@
+package imaging
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/ramadoka/penguin-logic/pkg/color"
+	"github.com/ramadoka/penguin-logic/pkg/euclidean"
+)
+
+// ExpiryStyle is the color of the pill shown under time-limited items, red
+// when the item is about to expire.
+type ExpiryStyle int
+
+const (
+	ExpiryUnknown ExpiryStyle = iota
+	ExpiryRed
+	ExpiryGreen
+)
+
+func (s ExpiryStyle) String() string {
+	switch s {
+	case ExpiryRed:
+		return "red"
+	case ExpiryGreen:
+		return "green"
+	default:
+		return "unknown"
+	}
+}
+
+// Expiry is the pill read under a depot cell. Text is empty, and Days and
+// Confidence zero, when the day count could not be read.
+type Expiry struct {
+	Style      ExpiryStyle
+	Days       int
+	Text       string
+	Bound      euclidean.IBound
+	Confidence float64
+}
+
+// The pill straddles the bottom of the ring; it reads "<clock> N days" in
+// white glyphs a bit smaller than the count badge ones.
+const (
+	pillLeft      = -0.35
+	pillRight     = 0.45
+	pillTop       = 0.42
+	pillBottom    = 0.7
+	pillMinHeight = 0.07
+	pillMaxHeight = 0.12
+	// pillCoverage is the share of the pill region its tone must cover.
+	pillCoverage = 0.25
+	// pillSpace is the gap, in glyph heights, separating the day count from
+	// the word following it.
+	pillSpace = 0.35
+)
+
+var pillTones = map[ExpiryStyle]func([3]float64) bool{
+	ExpiryRed: func(c [3]float64) bool {
+		h, s, v := color.HSV(c[0]/255, c[1]/255, c[2]/255)
+		return (h >= 320 || h < 20) && s >= 0.35 && v >= 0.3
+	},
+	ExpiryGreen: func(c [3]float64) bool {
+		h, s, v := color.HSV(c[0]/255, c[1]/255, c[2]/255)
+		return h >= 70 && h < 150 && s >= 0.35 && v >= 0.3
+	},
+}
+
+// DetectExpiry looks for an expiry pill under the cell in bound, and false
+// when there is none. The style is the tone covering most of the pill
+// region and the confidence the one of the day count reading.
+func DetectExpiry(img Image, bound euclidean.IBound) (Expiry, bool) {
+	size := float64(min(int(bound.Width()), int(bound.Height())))
+	if size <= 0 {
+		return Expiry{}, false
+	}
+	center := bound.Center()
+	region := euclidean.Bound(
+		euclidean.P2(center.X+euclidean.X(pillLeft*size), center.Y+euclidean.Y(pillTop*size)),
+		euclidean.P2(center.X+euclidean.X(pillRight*size), center.Y+euclidean.Y(pillBottom*size)),
+	)
+
+	style, covered := ExpiryUnknown, 0
+	var pill euclidean.IBound
+	for _, candidate := range []ExpiryStyle{ExpiryRed, ExpiryGreen} {
+		tone := pillTones[candidate]
+		count := 0
+		left, top := region.Right(), region.Bottom()
+		right, bottom := region.Left(), region.Top()
+		for y := region.Top(); y < region.Bottom(); y++ {
+			for x := region.Left(); x < region.Right(); x++ {
+				p := euclidean.P2(x, y)
+				if !inside(img, p) || !tone(rgb8(img, p)) {
+					continue
+				}
+				count++
+				left, right = min(left, x), max(right, x)
+				top, bottom = min(top, y), max(bottom, y)
+			}
+		}
+		if count > covered {
+			style, covered = candidate, count
+			pill = euclidean.Bound(euclidean.P2(left, top), euclidean.P2(right+1, bottom+1))
+		}
+	}
+	if float64(covered) < pillCoverage*float64(region.Area()) {
+		return Expiry{}, false
+	}
+
+	expiry := Expiry{Style: style, Bound: pill}
+	mask, w, h := inkMask(img, region, pillTones[style])
+	comps := framed(img, components(mask, w, h, region.TopLeft()), pillTones[style])
+	for _, line := range readLines(comps, int(math.Round(pillMinHeight*size)), int(math.Round(pillMaxHeight*size))) {
+		count := readRun(firstWord(line.glyphs), nil)
+		days, err := strconv.Atoi(count.text)
+		if err != nil {
+			continue
+		}
+		expiry.Days, expiry.Text, expiry.Confidence = days, count.text, count.confidence
+		break
+	}
+	return expiry, true
+}
+
+// firstWord returns the glyphs of a run up to the first gap wider than
+// pillSpace glyph heights.
+func firstWord(glyphs []component) []component {
+	for idx := 1; idx < len(glyphs); idx++ {
+		prev := glyphs[idx-1]
+		if float64(glyphs[idx].left-prev.right-1) > pillSpace*float64(prev.height()) {
+			return glyphs[:idx]
+		}
+	}
+	return glyphs
+}
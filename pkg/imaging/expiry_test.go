@@ -0,0 +1,32 @@
+package imaging_test
+
+import (
+	"testing"
+
+	"github.com/ramadoka/penguin-logic/pkg/imaging"
+)
+
+func TestDetectExpiry(t *testing.T) {
+	expected := map[imaging.LatticeCell]imaging.Expiry{
+		{Row: 1, Col: 4}: {Style: imaging.ExpiryRed, Days: 2},
+		{Row: 2, Col: 4}: {Style: imaging.ExpiryGreen, Days: 9},
+	}
+	img, placements := depot(t)
+	for _, p := range placements {
+		expiry, ok := imaging.DetectExpiry(img, p.CellBound)
+		want, expiring := expected[p.Cell]
+		if ok != expiring {
+			t.Errorf("cell %+v: expected pill %v, got %v", p.Cell, expiring, ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if expiry.Style != want.Style || expiry.Days != want.Days {
+			t.Errorf("cell %+v: expected %s %d days, got %s %d days", p.Cell, want.Style, want.Days, expiry.Style, expiry.Days)
+		}
+		if !p.CellBound.Contains(expiry.Bound.TopLeft()) {
+			t.Errorf("cell %+v: pill %s starts outside of the cell", p.Cell, expiry.Bound.ToString())
+		}
+	}
+}
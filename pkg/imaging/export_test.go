@@ -0,0 +1,109 @@
+package imaging
+
+import (
+	"github.com/ramadoka/penguin-logic/pkg/color"
+	"github.com/ramadoka/penguin-logic/pkg/euclidean"
+)
+
+// IntegralSequential builds the tables the way Integral used to, one table
+// after the other and point by point. It is only kept to check and to
+// benchmark Integral against.
+func IntegralSequential(img Image) (IntegralImage, error) {
+	i := img.(*image_)
+	w, h := i.Width(), i.Height()
+	values := [4][]uint16{
+		color.ChannelRed:   i.plane(planeRed),
+		color.ChannelGreen: i.plane(planeGreen),
+		color.ChannelBlue:  i.plane(planeBlue),
+		color.ChannelGray:  i.grays(),
+	}
+	sums := [4][]uint64{}
+	squares := [4][]uint64{}
+	for channel, v := range values {
+		sums[channel] = integratePointwise(w, h, v)
+		squared := make([]uint64, len(v))
+		for idx, value := range v {
+			squared[idx] = uint64(value) * uint64(value)
+		}
+		squares[channel] = integratePointwise(w, h, squared)
+	}
+	return integral{
+		reds:    sums[color.ChannelRed],
+		greens:  sums[color.ChannelGreen],
+		blues:   sums[color.ChannelBlue],
+		grays:   sums[color.ChannelGray],
+		squares: squares,
+		stride:  int(w),
+		w:       w,
+		h:       h,
+	}, nil
+}
+
+// BuildIntegral builds the tables of img the way Integral does, but every
+// time: Integral keeps them in the memo store img shares with its views, so
+// that timing it only times a lookup past the first call.
+func BuildIntegral(img Image) (IntegralImage, error) {
+	i := img.(*image_)
+	frame, err := Integral(*i)
+	if err != nil {
+		return nil, err
+	}
+	return frame.window(i), nil
+}
+
+func integratePointwise[T ~uint16 | ~uint64](width euclidean.W, height euclidean.H, colors []T) []uint64 {
+	result := make([]uint64, width.Mul(height))
+	for i := range result {
+		coord := euclidean.FromIndex(width, height, i)
+		colorUp := getColorAt(width, height, result, coord.Up())
+		colorLeft := getColorAt(width, height, result, coord.Left())
+		colorDiag := getColorAt(width, height, result, coord.Left().Up())
+		result[i] = colorUp + colorLeft - colorDiag + uint64(colors[i])
+	}
+	return result
+}
+
+func getColorAt[T ~uint16 | ~uint32 | ~uint64](w euclidean.W, h euclidean.H, colors []T, coord euclidean.Point) T {
+	zero := euclidean.Point{X: 0, Y: 0}
+	if coord.X < 0 {
+		return 0
+	}
+	if coord.Y < 0 {
+		return 0
+	}
+	if coord.X >= zero.X.Add(w) {
+		return 0
+	}
+	if coord.Y >= zero.Y.Add(h) {
+		return 0
+	}
+	index := coord.ToIndex0(w)
+	return colors[index]
+}
+
+// SamePixels tells whether a and b are laid over the same pixel storage.
+func SamePixels(a, b Image) bool {
+	pa, pb := a.(*image_).pix, b.(*image_).pix
+	return len(pa) > 0 && len(pa) == len(pb) && &pa[0] == &pb[0]
+}
+
+// IntegralTables returns the plain tables of i followed by its squared ones,
+// in color.Channel order.
+func IntegralTables(i IntegralImage) [8][]uint64 {
+	in := i.(integral)
+	return [8][]uint64{in.reds, in.greens, in.blues, in.grays, in.squares[0], in.squares[1], in.squares[2], in.squares[3]}
+}
+
+// IconColors returns the mean colors, from 0 to 1, of the icon grid cells of
+// bound, row by row.
+func IconColors(i IntegralImage, bound euclidean.IBound) ([][3]float64, error) {
+	cells, err := describeIcon(i, bound)
+	if err != nil {
+		return nil, err
+	}
+	colors := make([][3]float64, len(cells))
+	for idx, cell := range cells {
+		colors[idx] = cell.rgb
+	}
+	return colors, nil
+}
@@ -1,17 +1,34 @@
 package imaging
 
 import (
+	"image"
+
+	c "image/color"
+
 	"github.com/ramadoka/penguin-logic/pkg/color"
 	"github.com/ramadoka/penguin-logic/pkg/euclidean"
 	"github.com/ramadoka/penguin-logic/pkg/memoize"
 )
 
+// The pixels of an image_ are stored once, in four planes laid one after
+// the other in pix: red, green, blue and alpha, one value per pixel of the
+// frame each, row by row. The image itself may only show part of the frame,
+// when it is a SubImage view.
+// Values follow the depth model of pkg/color: alpha-premultiplied, 16-bit.
+const (
+	planeRed = iota
+	planeGreen
+	planeBlue
+	planeAlpha
+	planes
+)
+
 func (img *image_) _topLeft() euclidean.Point {
-	topLeft := euclidean.P(img.i.Bounds().Min)
+	topLeft := euclidean.P(img.bounds.Min)
 	return topLeft
 }
 func (img *image_) _bottomRight() euclidean.Point {
-	bottomRight := euclidean.P(img.i.Bounds().Max)
+	bottomRight := euclidean.P(img.bounds.Max)
 	return bottomRight
 }
 
@@ -23,67 +40,186 @@ func (img *image_) _height() euclidean.H {
 	return img.BottomRight().Y.Dist(img.TopLeft().Y)
 }
 
-func (img *image_) colors() [5][]uint32 {
-	return memoize.Memoize("colors", img.memoizer, img._colors)
+// plane returns the values of one plane over the whole frame, row by row.
+func (img *image_) plane(k int) []uint16 {
+	size := img.frame.Dx() * img.frame.Dy()
+	return img.pix[k*size : (k+1)*size]
 }
 
-func Colors(img *image_) [5][]uint32 {
-	return img._colors()
+// index is the position, in a plane, of the pixel at x, y in the
+// coordinates of the image.
+func (img *image_) index(x, y int) int {
+	return (y+img.offset.Y-img.frame.Min.Y)*img.frame.Dx() + x + img.offset.X - img.frame.Min.X
 }
 
-func (img *image_) _colors() [5][]uint32 {
-	colors := [5][]uint32{}
-	for y := img.Top(); y < img.Bottom(); y++ {
-		for x := img.Left(); x < img.Right(); x++ {
-			r, g, b, a := img.i.At(int(x), int(y)).RGBA()
-			colors[0] = append(colors[0], r)
-			colors[1] = append(colors[1], g)
-			colors[2] = append(colors[2], b)
-			colors[3] = append(colors[3], a)
-			gray := (r + g + b) / 3
-			colors[4] = append(colors[4], gray)
-		}
-	}
-	return colors
+// row returns the part of values, laid over the frame, shown on row y of
+// the image.
+func (img *image_) row(values []uint16, y int) []uint16 {
+	start := img.index(img.bounds.Min.X, y)
+	return values[start : start+img.bounds.Dx()]
 }
 
-func (i *image_) reds() []color.Red {
-	return memoize.Memoize("reds", i.memoizer, i._reds)
+// window is the part of the frame the image shows, in frame coordinates.
+func (img *image_) window() image.Rectangle {
+	return img.bounds.Add(img.offset)
 }
 
-func (i *image_) greens() []color.Green {
-	return memoize.Memoize("greens", i.memoizer, i._greens)
+func (img *image_) grays() []uint16 {
+	return memoize.Memoize("grays", img.shared, img._grays)
 }
 
-func (i *image_) blues() []color.Blue {
-	return memoize.Memoize("blues", i.memoizer, i._blues)
-}
-
-func (i *image_) grays() []color.Gray {
-	return memoize.Memoize("grays", i.memoizer, i._grays)
-}
-
-func (i *image_) _reds() []color.Red {
-	return wrap(i, 0, color.R)
-}
-
-func (i *image_) _greens() []color.Green {
-	return wrap(i, 1, color.G)
+func (img *image_) _grays() []uint16 {
+	reds, greens, blues := img.plane(planeRed), img.plane(planeGreen), img.plane(planeBlue)
+	grays := make([]uint16, len(reds))
+	for idx := range grays {
+		grays[idx] = uint16((uint32(reds[idx]) + uint32(greens[idx]) + uint32(blues[idx])) / 3)
+	}
+	return grays
+}
+
+// channel returns the values of any registered channel over the frame: a
+// plane, the grays, or the output of the channel function, computed once
+// for the image and its views.
+func (img *image_) channel(c color.Channel) ([]uint16, error) {
+	switch c {
+	case color.ChannelRed:
+		return img.plane(planeRed), nil
+	case color.ChannelGreen:
+		return img.plane(planeGreen), nil
+	case color.ChannelBlue:
+		return img.plane(planeBlue), nil
+	case color.ChannelGray:
+		return img.grays(), nil
+	}
+	fn, err := c.Func()
+	if err != nil {
+		return nil, err
+	}
+	return memoize.Memoize(c.String(), img.shared, func() []uint16 {
+		return img.derive(fn)
+	}), nil
 }
 
-func (i *image_) _blues() []color.Blue {
-	return wrap(i, 2, color.B)
+func (img *image_) derive(fn color.Func) []uint16 {
+	reds, greens, blues, alphas := img.plane(planeRed), img.plane(planeGreen), img.plane(planeBlue), img.plane(planeAlpha)
+	values := make([]uint16, len(reds))
+	for idx := range values {
+		values[idx] = fn(reds[idx], greens[idx], blues[idx], alphas[idx])
+	}
+	return values
 }
 
-func (i *image_) _grays() []color.Gray {
-	return wrap(i, 4, color.BW)
+// Colors widens the planes of img, followed by its gray one, to 32 bits.
+func Colors(img *image_) [5][]uint32 {
+	colors := [5][]uint32{}
+	for idx, values := range [5][]uint16{
+		img.plane(planeRed),
+		img.plane(planeGreen),
+		img.plane(planeBlue),
+		img.plane(planeAlpha),
+		img.grays(),
+	} {
+		colors[idx] = make([]uint32, 0, img.bounds.Dx()*img.bounds.Dy())
+		for y := img.bounds.Min.Y; y < img.bounds.Max.Y; y++ {
+			for _, v := range img.row(values, y) {
+				colors[idx] = append(colors[idx], uint32(v))
+			}
+		}
+	}
+	return colors
 }
 
-func wrap[T ~uint32](img *image_, colorIdx int, wrapper func(uint32) T) []T {
-	values := img.colors()[colorIdx]
-	wrapped := make([]T, len(values))
-	for i, v := range values {
-		wrapped[i] = wrapper(v)
+// decode copies i in planar storage, reading the pixel buffers of the
+// common concrete types directly and going through At for the others.
+func decode(i image.Image) []uint16 {
+	b := i.Bounds()
+	w, h := b.Dx(), b.Dy()
+	size := w * h
+	pix := make([]uint16, planes*size)
+	r, g, bl, a := pix[:size], pix[size:2*size], pix[2*size:3*size], pix[3*size:]
+	switch src := i.(type) {
+	case *image.RGBA:
+		for y := 0; y < h; y++ {
+			row := src.Pix[src.PixOffset(b.Min.X, b.Min.Y+y):]
+			for x := 0; x < w; x++ {
+				idx := y*w + x
+				r[idx] = color.From8(row[4*x])
+				g[idx] = color.From8(row[4*x+1])
+				bl[idx] = color.From8(row[4*x+2])
+				a[idx] = color.From8(row[4*x+3])
+			}
+		}
+	case *image.NRGBA:
+		for y := 0; y < h; y++ {
+			row := src.Pix[src.PixOffset(b.Min.X, b.Min.Y+y):]
+			for x := 0; x < w; x++ {
+				idx := y*w + x
+				// premultiplied as color.NRGBA.RGBA does
+				alpha := uint32(row[4*x+3])
+				r[idx] = uint16(uint32(row[4*x]) * 0x101 * alpha / 0xff)
+				g[idx] = uint16(uint32(row[4*x+1]) * 0x101 * alpha / 0xff)
+				bl[idx] = uint16(uint32(row[4*x+2]) * 0x101 * alpha / 0xff)
+				a[idx] = uint16(alpha * 0x101)
+			}
+		}
+	case *image.YCbCr:
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				idx := y*w + x
+				yi := src.YOffset(b.Min.X+x, b.Min.Y+y)
+				ci := src.COffset(b.Min.X+x, b.Min.Y+y)
+				cr, cg, cb, _ := c.YCbCr{Y: src.Y[yi], Cb: src.Cb[ci], Cr: src.Cr[ci]}.RGBA()
+				r[idx], g[idx], bl[idx], a[idx] = uint16(cr), uint16(cg), uint16(cb), color.Max
+			}
+		}
+	default:
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				idx := y*w + x
+				cr, cg, cb, ca := i.At(b.Min.X+x, b.Min.Y+y).RGBA()
+				r[idx], g[idx], bl[idx], a[idx] = uint16(cr), uint16(cg), uint16(cb), uint16(ca)
+			}
+		}
+	}
+	return pix
+}
+
+// encode rebuilds a standard image from the planes, on 8 bits per channel
+// when it loses nothing and on 16 bits otherwise.
+func (img *image_) encode() image.Image {
+	b := img.bounds
+	r, g, bl, a := img.plane(planeRed), img.plane(planeGreen), img.plane(planeBlue), img.plane(planeAlpha)
+	eightBits := true
+scan:
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for k := 0; k < planes; k++ {
+			for _, v := range img.row(img.plane(k), y) {
+				if v%0x101 != 0 {
+					eightBits = false
+					break scan
+				}
+			}
+		}
+	}
+	if eightBits {
+		out := image.NewRGBA(b)
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			row := out.Pix[out.PixOffset(b.Min.X, y):]
+			start := img.index(b.Min.X, y)
+			for x := 0; x < b.Dx(); x++ {
+				idx := start + x
+				row[4*x], row[4*x+1], row[4*x+2], row[4*x+3] = color.To8(r[idx]), color.To8(g[idx]), color.To8(bl[idx]), color.To8(a[idx])
+			}
+		}
+		return out
+	}
+	out := image.NewRGBA64(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		start := img.index(b.Min.X, y)
+		for x := 0; x < b.Dx(); x++ {
+			idx := start + x
+			out.SetRGBA64(b.Min.X+x, y, c.RGBA64{R: r[idx], G: g[idx], B: bl[idx], A: a[idx]})
+		}
 	}
-	return wrapped
+	return out
 }
@@ -1,12 +1,15 @@
 package imaging
 
 import (
+	"bytes"
+	"fmt"
 	"image"
+	"io"
 	"os"
 
-	c "image/color"
+	_ "image/gif"  // register GIF format
 	_ "image/jpeg" // register JPEG format
-	"image/png"
+	_ "image/png"  // register PNG format
 
 	"github.com/ramadoka/penguin-logic/pkg/color"
 	"github.com/ramadoka/penguin-logic/pkg/euclidean"
@@ -14,7 +17,16 @@ import (
 )
 
 type image_ struct {
-	i        image.Image
+	// pix holds the planes described in fn.go, laid over frame. The image
+	// shows bounds, which is the window of the frame at offset for views
+	// and the frame itself otherwise.
+	pix    []uint16
+	frame  image.Rectangle
+	offset image.Point
+	bounds image.Rectangle
+	// shared memoizes what is computed over the frame, for the image and
+	// its views alike, and memoizer what depends on bounds.
+	shared   memoize.IStore
 	memoizer memoize.IStore
 }
 
@@ -33,91 +45,220 @@ type Image interface {
 	Integral() (IntegralImage, error)
 	Invert() Image
 	Crop(b euclidean.IBound) Image
+	SubImage(b euclidean.IBound) Image
+	Resize(w euclidean.W, h euclidean.H, filter Filter) (Image, error)
+	ScaleBy(factor float64, filter Filter) (Image, error)
+	Warp(t euclidean.Homography, w euclidean.W, h euclidean.H) (Image, error)
 	Save(path string) error
-	Extract(channel color.Channel) image.Image
+	SaveAs(path string, opts SaveOptions) error
+	Encode(w io.Writer, opts SaveOptions) error
+	Extract(channel color.Channel) (image.Image, error)
 }
 
-func Load(path string) (Image, error) {
+// Limits bounds the size of the images a loader decodes, checked on the
+// image header before any pixel is read. A zero field is no limit.
+type Limits struct {
+	MaxWidth  int
+	MaxHeight int
+	MaxPixels int64
+}
+
+// DefaultLimits are the limits of Load, LoadReader and LoadBytes: 8K screens
+// fit, but a forged header asking for gigabytes does not.
+var DefaultLimits = Limits{MaxWidth: 16384, MaxHeight: 16384, MaxPixels: 64 << 20}
+
+// TooLargeError reports an image whose header exceeds the loader limits.
+type TooLargeError struct {
+	Width  int
+	Height int
+	Limits Limits
+}
+
+func (e *TooLargeError) Error() string {
+	return fmt.Sprintf("image of %d x %d exceeds the limits of %d x %d and %d pixels",
+		e.Width, e.Height, e.Limits.MaxWidth, e.Limits.MaxHeight, e.Limits.MaxPixels)
+}
+
+func (l Limits) check(config image.Config) error {
+	tooLarge := (l.MaxWidth > 0 && config.Width > l.MaxWidth) ||
+		(l.MaxHeight > 0 && config.Height > l.MaxHeight) ||
+		(l.MaxPixels > 0 && int64(config.Width)*int64(config.Height) > l.MaxPixels)
+	if tooLarge {
+		return &TooLargeError{Width: config.Width, Height: config.Height, Limits: l}
+	}
+	return nil
+}
+
+func (l Limits) Load(path string) (Image, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
+	return l.LoadReader(f)
+}
 
-	img, _, err := image.Decode(f)
+// LoadReader decodes the header of r, checks it against the limits and only
+// then decodes the pixels, r being read once.
+func (l Limits) LoadReader(r io.Reader) (Image, error) {
+	var header bytes.Buffer
+	config, _, err := image.DecodeConfig(io.TeeReader(r, &header))
+	if err != nil {
+		return nil, err
+	}
+	if err := l.check(config); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(io.MultiReader(&header, r))
 	if err != nil {
 		return nil, err
 	}
 	return New(img), nil
 }
 
+func (l Limits) LoadBytes(data []byte) (Image, error) {
+	return l.LoadReader(bytes.NewReader(data))
+}
+
+func Load(path string) (Image, error) {
+	return DefaultLimits.Load(path)
+}
+
+func LoadReader(r io.Reader) (Image, error) {
+	return DefaultLimits.LoadReader(r)
+}
+
+func LoadBytes(data []byte) (Image, error) {
+	return DefaultLimits.LoadBytes(data)
+}
+
+// New decodes i once in planar storage; the image is not read afterwards.
 func New(i image.Image) Image {
-	store := memoize.Store()
-	return &image_{i, store}
+	return stored(decode(i), i.Bounds())
 }
 
+// blank returns an image of the given bounds with every plane set to zero.
+func blank(bounds image.Rectangle) *image_ {
+	return stored(make([]uint16, planes*bounds.Dx()*bounds.Dy()), bounds)
+}
+
+func stored(pix []uint16, bounds image.Rectangle) *image_ {
+	return &image_{
+		pix:      pix,
+		frame:    bounds,
+		bounds:   bounds,
+		shared:   memoize.Store(),
+		memoizer: memoize.Store(),
+	}
+}
+
+// rectangle converts a bound to the image.Rectangle of the same pixels.
+func rectangle(b euclidean.IBound) image.Rectangle {
+	return image.Rect(int(b.Left()), int(b.Top()), int(b.Right()), int(b.Bottom()))
+}
+
+// Crop copies the pixels inside bound, at their 16-bit depth, in a new image
+// whose top left corner is the origin. The pixels of bound lying outside of
+// the image are left transparent black.
 func (i *image_) Crop(bound euclidean.IBound) Image {
-	cropped := image.NewRGBA(image.Rect(0, 0, int(bound.Width()), int(bound.Height())))
-	inners := bound.InnerCoords()
-	for _, coord := range inners {
-		shifted := coord.ShiftNeg(bound.TopLeft())
-		cropped.Set(int(shifted.X), int(shifted.Y), i.i.At(int(coord.X), int(coord.Y)))
+	r := rectangle(bound)
+	cropped := blank(image.Rect(0, 0, r.Dx(), r.Dy()))
+	visible := r.Intersect(i.bounds)
+	for k := 0; k < planes; k++ {
+		src, dst := i.plane(k), cropped.plane(k)
+		for y := visible.Min.Y; y < visible.Max.Y; y++ {
+			from := i.index(visible.Min.X, y)
+			to := (y-r.Min.Y)*r.Dx() + visible.Min.X - r.Min.X
+			copy(dst[to:to+visible.Dx()], src[from:from+visible.Dx()])
+		}
 	}
-	return New(cropped)
+	return cropped
 }
 
-func (i *image_) Save(path string) error {
-	f, err := os.Create(path)
-	if err != nil {
-		return err
+// SubImage is Crop without the copy: the view shares the pixels of the
+// image, the planes computed from them and their integral tables. Its top
+// left corner is the origin, and bound is clipped to the image.
+func (i *image_) SubImage(bound euclidean.IBound) Image {
+	visible := rectangle(bound).Intersect(i.bounds)
+	return &image_{
+		pix:      i.pix,
+		frame:    i.frame,
+		offset:   visible.Min.Add(i.offset),
+		bounds:   image.Rect(0, 0, visible.Dx(), visible.Dy()),
+		shared:   i.shared,
+		memoizer: memoize.Store(),
 	}
-	defer f.Close()
-	return png.Encode(f, i.i)
 }
 
+// Save writes the image in the format of the extension of path.
+func (i *image_) Save(path string) error {
+	return i.SaveAs(path, SaveOptions{})
+}
+
+func (i *image_) SaveAs(path string, opts SaveOptions) error {
+	return SaveImage(i.encode(), path, opts)
+}
+
+func (i *image_) Encode(w io.Writer, opts SaveOptions) error {
+	return EncodeImage(w, i.encode(), opts)
+}
+
+// Invert complements every channel on the full 16-bit depth and keeps the
+// alpha, so that the dark glyphs of an inverted image sum high.
 func (i *image_) Invert() Image {
-	newImage := image.NewRGBA(i.i.Bounds())
-	for y := 0; y < newImage.Bounds().Dy(); y++ {
-		for x := 0; x < newImage.Bounds().Dx(); x++ {
-			// coord := euclidean.P2(euclidean.X(x), euclidean.Y(y))
-			oldColor := i.i.At(x, y)
-			r, g, b, a := oldColor.RGBA()
-			newColor := c.RGBA{R: 255 - uint8(r), G: 255 - uint8(g), B: 255 - uint8(b), A: uint8(a)}
-			newImage.Set(x, y, newColor)
+	inverted := blank(i.bounds)
+	for y := i.bounds.Min.Y; y < i.bounds.Max.Y; y++ {
+		alphas := i.row(i.plane(planeAlpha), y)
+		copy(inverted.row(inverted.plane(planeAlpha), y), alphas)
+		for _, k := range []int{planeRed, planeGreen, planeBlue} {
+			dst := inverted.row(inverted.plane(k), y)
+			for x, v := range i.row(i.plane(k), y) {
+				dst[x] = color.Invert(v, alphas[x])
+			}
 		}
 	}
-	return &image_{
-		newImage,
-		memoize.Store(),
-	}
+	return inverted
 }
 
-func (i *image_) Extract(channel color.Channel) image.Image {
-	newImage := image.NewRGBA(i.i.Bounds())
-	for y := 0; y < newImage.Bounds().Dy(); y++ {
-		for x := 0; x < newImage.Bounds().Dx(); x++ {
-			coord := euclidean.P2(euclidean.X(x), euclidean.Y(y))
-			newColor := c.RGBA{}
-			// other := 0
-			switch channel {
-			case color.ChannelRed:
-				v := uint8(i.Red(coord) >> 8)
-				newColor = c.RGBA{R: v, A: 255}
-			case color.ChannelGreen:
-				v := uint8(i.Green(coord) >> 8)
-				newColor = c.RGBA{G: v, A: 255}
-			case color.ChannelBlue:
-				v := uint8(i.Blue(coord) >> 8)
-				newColor = c.RGBA{B: v, A: 255}
+// Extract draws one channel of the image, narrowed to 8 bits, on an opaque
+// black background: red, green and blue in their own color and the other
+// channels in shades of gray.
+func (i *image_) Extract(channel color.Channel) (image.Image, error) {
+	values, err := i.channel(channel)
+	if err != nil {
+		return nil, err
+	}
+	newImage := image.NewRGBA(i.bounds)
+	offsets := []int{0, 1, 2}
+	switch channel {
+	case color.ChannelRed, color.ChannelGreen, color.ChannelBlue:
+		offsets = []int{int(channel)}
+	}
+	for y := i.bounds.Min.Y; y < i.bounds.Max.Y; y++ {
+		row := newImage.Pix[newImage.PixOffset(i.bounds.Min.X, y):]
+		for x, v := range i.row(values, y) {
+			for _, offset := range offsets {
+				row[4*x+offset] = color.To8(v)
 			}
-			newImage.Set(x, y, newColor)
+			row[4*x+3] = color.Max8
 		}
 	}
-	return newImage
+	return newImage, nil
 }
 
+// Integral builds the tables over the frame once, for the image and its
+// views, and reads them through the window of i.
 func (i *image_) Integral() (IntegralImage, error) {
-	return Integral(*i)
+	if i.bounds.Empty() {
+		return nil, fmt.Errorf("invalid dimensions: %d x %d", i.bounds.Dx(), i.bounds.Dy())
+	}
+	frame, err := memoize.Memoize2("integral", i.shared, func() (integral, error) {
+		return Integral(*i)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return frame.window(i), nil
 }
 
 func (img *image_) TopLeft() euclidean.Point {
@@ -153,16 +294,13 @@ func (img *image_) Right() euclidean.X {
 }
 
 func (img *image_) Red(point euclidean.Point) color.Red {
-	index := point.ToIndex(img.TopLeft(), img.Width())
-	return img.reds()[index]
+	return color.Red(img.plane(planeRed)[img.index(int(point.X), int(point.Y))])
 }
 
 func (img *image_) Green(point euclidean.Point) color.Green {
-	index := point.ToIndex(img.TopLeft(), img.Width())
-	return img.greens()[index]
+	return color.Green(img.plane(planeGreen)[img.index(int(point.X), int(point.Y))])
 }
 
 func (img *image_) Blue(point euclidean.Point) color.Blue {
-	index := point.ToIndex(img.TopLeft(), img.Width())
-	return img.blues()[index]
+	return color.Blue(img.plane(planeBlue)[img.index(int(point.X), int(point.Y))])
 }
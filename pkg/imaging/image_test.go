@@ -1,9 +1,16 @@
 package imaging_test
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"image"
+	"image/png"
+	"strings"
 	"testing"
 
+	c "image/color"
+
 	"github.com/ramadoka/penguin-logic/pkg/color"
 	"github.com/ramadoka/penguin-logic/pkg/euclidean"
 	"github.com/ramadoka/penguin-logic/pkg/imaging"
@@ -19,6 +26,191 @@ func TestColor(t *testing.T) {
 	bound := euclidean.Bound(start, windowSize)
 
 	integral, _ := i.Integral()
-	blue := integral.Calculate(color.ChannelBlue, bound)
+	blue, err := integral.Calculate(color.ChannelBlue, bound)
+	if err != nil {
+		t.Fatal(err)
+	}
 	fmt.Println(blue)
 }
+
+func TestNewMatchesAt(t *testing.T) {
+	rgba := image.NewRGBA(image.Rect(2, 3, 19, 14))
+	nrgba := image.NewNRGBA(image.Rect(-4, 1, 13, 12))
+	for y := 0; y < 11; y++ {
+		for x := 0; x < 17; x++ {
+			rgba.Set(2+x, 3+y, c.RGBA{R: uint8(x * 15), G: uint8(y * 23), B: uint8(x * y), A: 255})
+			nrgba.Set(-4+x, 1+y, c.NRGBA{R: uint8(x * 15), G: uint8(y * 23), B: uint8(x * y), A: uint8(40 + x*12)})
+		}
+	}
+	ycbcr := image.NewYCbCr(image.Rect(0, 0, 16, 10), image.YCbCrSubsampleRatio420)
+	for idx := range ycbcr.Y {
+		ycbcr.Y[idx] = uint8(idx * 7)
+	}
+	for idx := range ycbcr.Cb {
+		ycbcr.Cb[idx], ycbcr.Cr[idx] = uint8(idx*11), uint8(255-idx*5)
+	}
+	gray := image.NewGray16(image.Rect(0, 0, 9, 4))
+	for idx := range gray.Pix {
+		gray.Pix[idx] = uint8(idx * 13)
+	}
+
+	sources := map[string]image.Image{
+		"rgba":     rgba,
+		"sub rgba": rgba.SubImage(image.Rect(5, 6, 12, 13)),
+		"nrgba":    nrgba,
+		"ycbcr":    ycbcr,
+		"gray16":   gray,
+	}
+	for name, src := range sources {
+		img := imaging.New(src)
+		b := src.Bounds()
+		if img.Left() != euclidean.X(b.Min.X) || img.Bottom() != euclidean.Y(b.Max.Y) {
+			t.Errorf("%s: expected bounds %v, got %s %s", name, b, img.TopLeft().ToString(), img.BottomRight().ToString())
+		}
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				r, g, bl, _ := src.At(x, y).RGBA()
+				p := euclidean.P2(euclidean.X(x), euclidean.Y(y))
+				if uint32(img.Red(p)) != r || uint32(img.Green(p)) != g || uint32(img.Blue(p)) != bl {
+					t.Fatalf("%s at %s: expected %d %d %d, got %d %d %d", name, p.ToString(), r, g, bl, img.Red(p), img.Green(p), img.Blue(p))
+				}
+			}
+		}
+	}
+}
+
+func encodePNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	src := image.NewRGBA(image.Rect(0, 0, w, h))
+	for idx := range src.Pix {
+		src.Pix[idx] = uint8(idx)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestLoadBytes(t *testing.T) {
+	img, err := imaging.LoadBytes(encodePNG(t, 12, 7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Width() != 12 || img.Height() != 7 {
+		t.Errorf("expected 12 x 7, got %d x %d", img.Width(), img.Height())
+	}
+	if _, err := imaging.LoadReader(strings.NewReader("not an image")); err == nil {
+		t.Error("expected an error on a corrupt image")
+	}
+}
+
+func TestLoadLimits(t *testing.T) {
+	data := encodePNG(t, 40, 30)
+	for _, limits := range []imaging.Limits{
+		{MaxWidth: 39},
+		{MaxHeight: 29},
+		{MaxPixels: 40*30 - 1},
+	} {
+		_, err := limits.LoadBytes(data)
+		var tooLarge *imaging.TooLargeError
+		if !errors.As(err, &tooLarge) {
+			t.Errorf("%+v: expected a TooLargeError, got %v", limits, err)
+			continue
+		}
+		if tooLarge.Width != 40 || tooLarge.Height != 30 {
+			t.Errorf("%+v: expected 40 x 30 in the error, got %d x %d", limits, tooLarge.Width, tooLarge.Height)
+		}
+	}
+	if _, err := (imaging.Limits{MaxWidth: 40, MaxHeight: 30, MaxPixels: 40 * 30}).LoadBytes(data); err != nil {
+		t.Errorf("expected an image at the limits to load, got %v", err)
+	}
+}
+
+func TestInvert(t *testing.T) {
+	gray := image.NewGray16(image.Rect(0, 0, 3, 1))
+	for x, v := range []uint16{0x1234, 0x00ff, 0xff00} {
+		gray.SetGray16(x, 0, c.Gray16{Y: v})
+	}
+	img := imaging.New(gray)
+	inverted := img.Invert()
+	for x, v := range []uint16{0x1234, 0x00ff, 0xff00} {
+		p := euclidean.P2(euclidean.X(x), 0)
+		if got := inverted.Red(p); got != color.Red(color.Max-v) {
+			t.Errorf("at %s: expected %#x, got %#x", p.ToString(), color.Max-v, got)
+		}
+		if back := inverted.Invert().Red(p); back != img.Red(p) {
+			t.Errorf("at %s: expected inverting twice to give %#x, got %#x", p.ToString(), img.Red(p), back)
+		}
+	}
+
+	translucent := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	translucent.SetNRGBA(0, 0, c.NRGBA{R: 255, G: 0, B: 64, A: 128})
+	r, g, b, a := translucent.At(0, 0).RGBA()
+	inverted = imaging.New(translucent).Invert()
+	origin := euclidean.P2(0, 0)
+	if uint32(inverted.Red(origin)) != a-r || uint32(inverted.Green(origin)) != a-g || uint32(inverted.Blue(origin)) != a-b {
+		t.Errorf("expected %#x %#x %#x against alpha %#x, got %#x %#x %#x",
+			a-r, a-g, a-b, a, inverted.Red(origin), inverted.Green(origin), inverted.Blue(origin))
+	}
+
+	red, err := img.Extract(color.ChannelRed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	extracted := red.At(0, 0).(c.RGBA)
+	if extracted.R != 0x12 || extracted.A != color.Max8 {
+		t.Errorf("expected the high byte 0x12 on an opaque pixel, got %+v", extracted)
+	}
+}
+
+func TestCropAndSubImage(t *testing.T) {
+	src := randomOpaque(t, 6, 4, 3)
+	img := imaging.New(src)
+	b := euclidean.Bound(euclidean.P2(1, 1), euclidean.P2(4, 3))
+	cropped, view := img.Crop(b), img.SubImage(b)
+	if !imaging.SamePixels(img, view) || imaging.SamePixels(img, cropped) {
+		t.Error("expected the view to share the pixels of the image and the crop to copy them")
+	}
+	for _, got := range []imaging.Image{cropped, view} {
+		if got.Width() != 3 || got.Height() != 2 || got.TopLeft() != euclidean.P2(0, 0) {
+			t.Fatalf("expected 3 x 2 pixels at the origin, got %d x %d at %s", got.Width(), got.Height(), got.TopLeft().ToString())
+		}
+		for y := euclidean.Y(0); y < 2; y++ {
+			for x := euclidean.X(0); x < 3; x++ {
+				p, q := euclidean.P2(x, y), euclidean.P2(x+1, y+1)
+				if got.Red(p) != img.Red(q) || got.Green(p) != img.Green(q) || got.Blue(p) != img.Blue(q) {
+					t.Errorf("at %s: expected the pixel at %s of the image", p.ToString(), q.ToString())
+				}
+			}
+		}
+	}
+
+	nested := view.SubImage(euclidean.Bound(euclidean.P2(1, 0), euclidean.P2(3, 2)))
+	if nested.Width() != 2 || nested.Red(euclidean.P2(0, 0)) != img.Red(euclidean.P2(2, 1)) {
+		t.Errorf("expected a view of a view to start at 2, 1 of the image, got %d wide", nested.Width())
+	}
+	var buf bytes.Buffer
+	if err := view.Encode(&buf, imaging.SaveOptions{Format: imaging.FormatPNG}); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Bounds() != image.Rect(0, 0, 3, 2) || decoded.At(0, 0) != src.At(1, 1) {
+		t.Errorf("expected the view to encode 3 x 2 pixels from 1, 1, got %s", decoded.Bounds())
+	}
+
+	outside := euclidean.Bound(euclidean.P2(4, 2), euclidean.P2(8, 6))
+	cropped = img.Crop(outside)
+	if cropped.Width() != 4 || cropped.Height() != 4 || cropped.Red(euclidean.P2(0, 0)) != img.Red(euclidean.P2(4, 2)) {
+		t.Errorf("expected a 4 x 4 crop starting at 4, 2, got %d x %d", cropped.Width(), cropped.Height())
+	}
+	if cropped.Red(euclidean.P2(3, 3)) != 0 {
+		t.Errorf("expected transparent black outside of the image, got %#x", cropped.Red(euclidean.P2(3, 3)))
+	}
+	if clipped := img.SubImage(outside); clipped.Width() != 2 || clipped.Height() != 2 {
+		t.Errorf("expected the view to be clipped to 2 x 2, got %d x %d", clipped.Width(), clipped.Height())
+	}
+}
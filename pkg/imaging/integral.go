@@ -3,27 +3,57 @@ package imaging
 import (
 	"fmt"
 	"math"
+	"sync"
 
 	"github.com/ramadoka/penguin-logic/pkg/color"
 	"github.com/ramadoka/penguin-logic/pkg/euclidean"
 )
 
+// integral keeps the running sums of every channel on 64 bits, the sums of
+// the 16-bit values of the pkg/color depth model overflowing 32 bits past
+// 65537 pixels.
 type integral struct {
-	reds   []color.Red
-	greens []color.Green
-	blues  []color.Blue
-	grays  []color.Gray
+	reds   []uint64
+	greens []uint64
+	blues  []uint64
+	grays  []uint64
+	// squares holds the integral of the squared values of every channel,
+	// indexed by color.Channel.
+	squares [4][]uint64
+	// derived holds the tables of the other registered channels, built from
+	// source the first time they are asked for.
+	derived *derivedTables
+	source  *image_
+	// The tables cover the frame of the image, stride pixels wide, and the
+	// integral reads the w x h window at ox, oy of it.
+	stride int
+	ox, oy int
 	w      euclidean.W
 	h      euclidean.H
 }
 
+type derivedTables struct {
+	mu      sync.Mutex
+	sums    map[color.Channel][]uint64
+	squares map[color.Channel][]uint64
+}
+
+// IntegralImage sums any registered channel over a bound in constant time.
+// Bounds are half-open, in coordinates relative to the top left corner of
+// the image, and clipped to it. The methods taking a channel fail with
+// color.ErrUnknownChannel for the others.
 type IntegralImage interface {
-	ApplyFeat(channel color.Channel, bound euclidean.IBound, pattern IPattern) int64
+	ApplyFeat(channel color.Channel, bound euclidean.IBound, pattern IPattern) (int64, error)
 	ExtractFeat(channel color.Channel, bound euclidean.IBound, pattern IPattern) []Feature
 	Guess(bound euclidean.IBound) ([]int64, bool)
-	Calculate(channel color.Channel, bound euclidean.IBound) int64
-	CenterOfMass(channel color.Channel, bound euclidean.IBound) euclidean.Point
-	BoundRecenter(channel color.Channel, bound euclidean.IBound, maxIter int) euclidean.IBound
+	Calculate(channel color.Channel, bound euclidean.IBound) (int64, error)
+	Mean(channel color.Channel, bound euclidean.IBound) (float64, error)
+	Variance(channel color.Channel, bound euclidean.IBound) (float64, error)
+	StdDev(channel color.Channel, bound euclidean.IBound) (float64, error)
+	CenterOfMass(channel color.Channel, bound euclidean.IBound) (euclidean.Point, error)
+	BoundRecenter(channel color.Channel, bound euclidean.IBound, maxIter int) (euclidean.IBound, error)
+	Width() euclidean.W
+	Height() euclidean.H
 }
 
 // 1234567890 => 1.234.567.890
@@ -35,38 +65,50 @@ func prettyPrint(n int) string {
 	return s
 }
 
-func (i integral) BoundRecenter(channel color.Channel, bound euclidean.IBound, maxIter int) euclidean.IBound {
+func (i integral) Width() euclidean.W {
+	return i.w
+}
+
+func (i integral) Height() euclidean.H {
+	return i.h
+}
+
+func (i integral) BoundRecenter(channel color.Channel, bound euclidean.IBound, maxIter int) (euclidean.IBound, error) {
 	centerOfBound := bound.Center()
-	if maxIter <= 0 {
-		return bound
+	centerOfMass, err := i.CenterOfMass(channel, bound)
+	if err != nil || maxIter <= 0 {
+		return bound, err
 	}
-	centerOfMass := i.CenterOfMass(channel, bound)
 	shift := centerOfMass.ShiftNeg(centerOfBound)
-	fmt.Printf("shift: %v\n", shift)
-	fmt.Printf("%d. enter of Bound: %v, Center of Mass: %v\n", maxIter, centerOfBound, centerOfMass)
 	dx := math.Abs(float64(shift.X))
 	dy := math.Abs(float64(shift.Y))
 	if dx < 5 && dy < 5 {
-		return bound
+		return bound, nil
 	}
 	newBound := bound.ShiftPos(shift)
-	fmt.Printf("Center of Bound: %v, Center of Mass: %v\n", centerOfBound, centerOfMass)
 	return i.BoundRecenter(channel, newBound, maxIter-1)
 }
 
-func (i integral) CenterOfMass(channel color.Channel, bound euclidean.IBound) euclidean.Point {
-	total := i.Calculate(channel, bound)
+// CenterOfMass returns the pixel holding the median of the channel mass of
+// bound along each axis: the first column, and row, up to which the mass
+// exceeds half of it.
+func (i integral) CenterOfMass(channel color.Channel, bound euclidean.IBound) (euclidean.Point, error) {
+	sums, _, err := i.tables(channel)
+	if err != nil {
+		return euclidean.Point{}, err
+	}
+	total := i.sum(sums, bound)
 	half := total / 2
-	limY := [2]euclidean.Y{bound.Top(), bound.Bottom()}
-	limX := [2]euclidean.X{bound.Left(), bound.Right()}
+	limY := [2]euclidean.Y{bound.Top(), bound.Bottom() - 1}
+	limX := [2]euclidean.X{bound.Left(), bound.Right() - 1}
 	for range 100 {
 		if limX[0] >= limX[1] {
 			break
 		}
 		newX := (limX[0] + limX[1]) / 2
-		bottomRight := euclidean.P2(euclidean.X(newX), bound.Bottom())
+		bottomRight := euclidean.P2(euclidean.X(newX+1), bound.Bottom())
 		partialBound := euclidean.Bound(bound.TopLeft(), bottomRight)
-		newSum := i.Calculate(channel, partialBound)
+		newSum := i.sum(sums, partialBound)
 		if newSum > half {
 			limX[1] = newX
 		} else {
@@ -79,9 +121,9 @@ func (i integral) CenterOfMass(channel color.Channel, bound euclidean.IBound) eu
 			break
 		}
 		newY := (limY[0] + limY[1]) / 2
-		bottomRight := euclidean.P2(bound.Right(), euclidean.Y(newY))
+		bottomRight := euclidean.P2(bound.Right(), euclidean.Y(newY+1))
 		partialBound := euclidean.Bound(bound.TopLeft(), bottomRight)
-		newSum := i.Calculate(channel, partialBound)
+		newSum := i.sum(sums, partialBound)
 		if newSum > half {
 			limY[1] = newY
 		} else {
@@ -89,21 +131,25 @@ func (i integral) CenterOfMass(channel color.Channel, bound euclidean.IBound) eu
 		}
 	}
 
-	return euclidean.P2(euclidean.X(limX[0]-1), euclidean.Y(limY[0]-1))
+	return euclidean.P2(limX[0], limY[0]), nil
 }
 
-func (i integral) ApplyFeat(channel color.Channel, bound euclidean.IBound, pattern IPattern) int64 {
+func (i integral) ApplyFeat(channel color.Channel, bound euclidean.IBound, pattern IPattern) (int64, error) {
+	sums, _, err := i.tables(channel)
+	if err != nil {
+		return 0, err
+	}
 	feats := i.ExtractFeat(channel, bound, pattern)
 	sum := int64(0)
 	for _, feat := range feats {
-		value := i.Calculate(channel, feat.Bound)
+		value := i.sum(sums, feat.Bound)
 		normalized := int64(feat.Multiplier) * int64(value)
 		sum += normalized
 		// fmt.Printf("%d. Feature %v: %d x %d = %d => %d\n", idx, feat.Bound, feat.Multiplier, value, normalized, sum)
 	}
 	// fmt.Printf("Sum: %d\n", sum)
 	// fmt.Println("---")
-	return sum
+	return sum, nil
 }
 
 func (i integral) ExtractFeat(channel color.Channel, bound euclidean.IBound, pattern IPattern) []Feature {
@@ -111,12 +157,17 @@ func (i integral) ExtractFeat(channel color.Channel, bound euclidean.IBound, pat
 	return split
 }
 
+// Guess applies FeatInner5 on red, green and blue and accepts bound when at
+// most one of them responds negatively.
 func (i integral) Guess(bound euclidean.IBound) ([]int64, bool) {
 	feat := FeatInner5()
 	outs := []int64{0, 0, 0}
 	offense := 0
 	for idx, channel := range color.Channels() {
-		v := i.ApplyFeat(channel, bound, feat)
+		v, err := i.ApplyFeat(channel, bound, feat)
+		if err != nil {
+			return outs, false
+		}
 		outs[idx] = v
 		if v < 0 {
 			offense++
@@ -126,112 +177,240 @@ func (i integral) Guess(bound euclidean.IBound) ([]int64, bool) {
 	return outs, offense <= 1
 }
 
-func (i integral) Calculate(channel color.Channel, bound euclidean.IBound) int64 {
-	topLeft := bound.TopLeft()
-	bottomRight := bound.BottomRight()
+func (i integral) Calculate(channel color.Channel, bound euclidean.IBound) (int64, error) {
+	sums, _, err := i.tables(channel)
+	if err != nil {
+		return 0, err
+	}
+	return i.sum(sums, bound), nil
+}
+
+// sum reads the sum of table over bound, clipped to the image. The corners
+// are combined in uint64, which only wraps when the region sum itself does
+// not fit, before being widened to int64.
+func (i integral) sum(table []uint64, bound euclidean.IBound) int64 {
+	clamped := bound.ClampTo(i.frame())
+	if clamped.IsEmpty() {
+		return 0
+	}
+	left, right := int(clamped.Left())+i.ox, int(clamped.Right())+i.ox
+	top, bottom := int(clamped.Top())+i.oy, int(clamped.Bottom())+i.oy
+	return int64(i.before(table, right, bottom) - i.before(table, left, bottom) - i.before(table, right, top) + i.before(table, left, top))
+}
+
+// before is the sum of the frame pixels left of x and above y.
+func (i integral) before(table []uint64, x, y int) uint64 {
+	if x == 0 || y == 0 {
+		return 0
+	}
+	return table[(y-1)*i.stride+x-1]
+}
+
+// tables returns the plain and squared tables of channel, building them
+// on first use for the channels other than red, green, blue and gray.
+func (i integral) tables(channel color.Channel) ([]uint64, []uint64, error) {
 	switch channel {
 	case color.ChannelRed:
-		return i.SumRed(topLeft, bottomRight)
+		return i.reds, i.squares[channel], nil
 	case color.ChannelGreen:
-		return i.SumGreen(topLeft, bottomRight)
+		return i.greens, i.squares[channel], nil
 	case color.ChannelBlue:
-		return i.SumBlue(topLeft, bottomRight)
+		return i.blues, i.squares[channel], nil
 	case color.ChannelGray:
-		return i.SumGray(topLeft, bottomRight)
+		return i.grays, i.squares[channel], nil
+	}
+	if i.derived == nil {
+		return nil, nil, fmt.Errorf("%w: %s has no source image", color.ErrUnknownChannel, channel)
+	}
+	return i.derived.get(i.source, channel)
+}
+
+func newDerivedTables() *derivedTables {
+	return &derivedTables{sums: map[color.Channel][]uint64{}, squares: map[color.Channel][]uint64{}}
+}
+
+// get builds the tables of a registered channel once; concurrent callers
+// wait for the first one.
+func (d *derivedTables) get(img *image_, channel color.Channel) ([]uint64, []uint64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if sums, ok := d.sums[channel]; ok {
+		return sums, d.squares[channel], nil
+	}
+	values, err := img.channel(channel)
+	if err != nil {
+		return nil, nil, err
+	}
+	w, h := img.frame.Dx(), img.frame.Dy()
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		d.sums[channel] = integrateRows(w, h, values, false)
+	}()
+	go func() {
+		defer wg.Done()
+		d.squares[channel] = integrateRows(w, h, values, true)
+	}()
+	wg.Wait()
+	return d.sums[channel], d.squares[channel], nil
+}
+
+// Mean is the mean value, from 0 to color.Max, of the pixels of bound lying
+// in the image, 0 when there are none.
+func (i integral) Mean(channel color.Channel, bound euclidean.IBound) (float64, error) {
+	sums, _, err := i.tables(channel)
+	if err != nil {
+		return 0, err
+	}
+	clamped, n := i.clamp(bound)
+	if n == 0 {
+		return 0, nil
+	}
+	return float64(i.sum(sums, clamped)) / float64(n), nil
+}
+
+// Variance is the population variance of the pixels of bound lying in the
+// image, computed from the plain and squared tables in constant time.
+func (i integral) Variance(channel color.Channel, bound euclidean.IBound) (float64, error) {
+	sums, squared, err := i.tables(channel)
+	if err != nil {
+		return 0, err
 	}
-	return 0
+	clamped, n := i.clamp(bound)
+	if n == 0 {
+		return 0, nil
+	}
+	mean := float64(i.sum(sums, clamped)) / float64(n)
+	squares := float64(i.sum(squared, clamped))
+	return max(squares/float64(n)-mean*mean, 0), nil
+}
+
+func (i integral) StdDev(channel color.Channel, bound euclidean.IBound) (float64, error) {
+	variance, err := i.Variance(channel, bound)
+	return math.Sqrt(variance), err
+}
+
+// frame is the bound of the whole image.
+func (i integral) frame() euclidean.IBound {
+	return euclidean.BoundAt(euclidean.P2(0, 0), i.w, i.h)
+}
+
+// clamp returns the part of bound lying in the image and the number of
+// pixels it holds.
+func (i integral) clamp(bound euclidean.IBound) (euclidean.IBound, int64) {
+	clamped := bound.ClampTo(i.frame())
+	return clamped, int64(clamped.Area())
 }
 
 func (i integral) SumRed(topLeft, bottomRight euclidean.Point) int64 {
-	return sumColor(i.w, i.h, i.reds, topLeft, bottomRight)
+	return i.sum(i.reds, euclidean.Bound(topLeft, bottomRight))
 }
 
 func (i integral) SumGray(topLeft, bottomRight euclidean.Point) int64 {
-	return sumColor(i.w, i.h, i.grays, topLeft, bottomRight)
+	return i.sum(i.grays, euclidean.Bound(topLeft, bottomRight))
 }
 
 func (i integral) SumGreen(topLeft, bottomRight euclidean.Point) int64 {
-	return sumColor(i.w, i.h, i.greens, topLeft, bottomRight)
+	return i.sum(i.greens, euclidean.Bound(topLeft, bottomRight))
 }
 
 func (i integral) SumBlue(topLeft, bottomRight euclidean.Point) int64 {
-	return sumColor(i.w, i.h, i.blues, topLeft, bottomRight)
+	return i.sum(i.blues, euclidean.Bound(topLeft, bottomRight))
 }
 
+// Integral builds the plain and squared tables of red, green, blue and gray
+// over the frame of image, each table in its own goroutine, and reads them
+// through the window image shows. The tables of the other registered
+// channels are only built when first used.
 func Integral(image image_) (integral, error) {
-	zero := integral{}
-	reds, err := Integrate(image.Width(), image.Height(), image.reds())
-	if err != nil {
-		return zero, err
+	w, h := image.Width(), image.Height()
+	if w == 0 || h == 0 {
+		return integral{}, fmt.Errorf("invalid dimensions: %d x %d", w, h)
 	}
-	greens, err := Integrate(image.Width(), image.Height(), image.greens())
-	if err != nil {
-		return zero, err
+	fw, fh := image.frame.Dx(), image.frame.Dy()
+	values := [4][]uint16{
+		color.ChannelRed:   image.plane(planeRed),
+		color.ChannelGreen: image.plane(planeGreen),
+		color.ChannelBlue:  image.plane(planeBlue),
+		color.ChannelGray:  image.grays(),
 	}
-	blues, err := Integrate(image.Width(), image.Height(), image.blues())
-	if err != nil {
-		return zero, err
+	sums := [4][]uint64{}
+	squares := [4][]uint64{}
+	var wg sync.WaitGroup
+	for channel := range values {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			sums[channel] = integrateRows(fw, fh, values[channel], false)
+		}()
+		go func() {
+			defer wg.Done()
+			squares[channel] = integrateRows(fw, fh, values[channel], true)
+		}()
 	}
-	grays, err := Integrate(image.Width(), image.Height(), image.grays())
-	if err != nil {
-		return zero, err
+	wg.Wait()
+	frame := integral{
+		reds:    sums[color.ChannelRed],
+		greens:  sums[color.ChannelGreen],
+		blues:   sums[color.ChannelBlue],
+		grays:   sums[color.ChannelGray],
+		squares: squares,
+		derived: newDerivedTables(),
+		source:  &image,
+		stride:  fw,
 	}
-	return integral{
-		reds:   reds,
-		greens: greens,
-		blues:  blues,
-		grays:  grays,
-		w:      image.Width(),
-		h:      image.Height(),
-	}, nil
+	return frame.window(&image), nil
 }
 
-func sumColor[T ~uint32](w euclidean.W, h euclidean.H, colors []T, topLeft, bottomRight euclidean.Point) int64 {
-	A := getColorAt(w, h, colors, bottomRight)
-	B := getColorAt(w, h, colors, euclidean.Point{X: topLeft.X - 1, Y: bottomRight.Y})
-	C := getColorAt(w, h, colors, euclidean.Point{X: bottomRight.X, Y: topLeft.Y - 1})
-	D := getColorAt(w, h, colors, euclidean.Point{X: topLeft.X - 1, Y: topLeft.Y - 1})
-	return int64(A - B - C + D)
+// window returns the integral of the frame read through the window img
+// shows, sharing the tables.
+func (i integral) window(img *image_) integral {
+	window := img.window()
+	i.ox, i.oy = window.Min.X-img.frame.Min.X, window.Min.Y-img.frame.Min.Y
+	i.w, i.h = euclidean.W(window.Dx()), euclidean.H(window.Dy())
+	return i
 }
 
-func getColorAt[T ~uint32](w euclidean.W, h euclidean.H, colors []T, coord euclidean.Point) T {
-	zero := euclidean.Point{X: 0, Y: 0}
-	if coord.X < 0 {
-		return 0
-	}
-	if coord.Y < 0 {
-		return 0
-	}
-	if coord.X >= zero.X.Add(w) {
-		return 0
-	}
-	if coord.Y >= zero.Y.Add(h) {
-		return 0
+// Integrate builds the integral table of colors on 64 bits, wide enough for
+// 2^32 pixels at the 16-bit depth of color.Color.RGBA.
+func Integrate[T ~uint16 | ~uint32 | ~uint64](width euclidean.W, height euclidean.H, colors []T) ([]uint64, error) {
+	if (len(colors) != int(width.Mul(height))) || width == 0 || height == 0 {
+		return nil, fmt.Errorf("invalid dimensions: %d x %d for %d colors", width, height, len(colors))
 	}
-	index := coord.ToIndex0(w)
-	return colors[index]
+	return integrateRows(int(width), int(height), colors, false), nil
 }
 
-func Integrate[T ~uint32](width euclidean.W, height euclidean.H, colors []T) ([]T, error) {
+// IntegrateSquares is Integrate over the squared values.
+func IntegrateSquares[T ~uint16 | ~uint32](width euclidean.W, height euclidean.H, colors []T) ([]uint64, error) {
 	if (len(colors) != int(width.Mul(height))) || width == 0 || height == 0 {
 		return nil, fmt.Errorf("invalid dimensions: %d x %d for %d colors", width, height, len(colors))
 	}
-	size := width.Mul(height)
-	result := make([]T, size)
-	for i := range int(size) {
-		coord := euclidean.FromIndex(width, height, i)
-		up := coord.Up()
-		colorUp := getColorAt(width, height, result, up)
-		// fmt.Printf("up: %v, colorUp: %d\n", up, colorUp)
-
-		left := coord.Left()
-		colorLeft := getColorAt(width, height, result, left)
-		// fmt.Printf("left: %v, colorLeft: %d\n", left, colorLeft)
+	return integrateRows(int(width), int(height), colors, true), nil
+}
 
-		diag := coord.Left().Up()
-		colorDiag := getColorAt(width, height, result, diag)
-		// fmt.Printf("diag: %v, colorDiag: %d\n", diag, colorDiag)
-		result[i] = colorUp + colorLeft - colorDiag + colors[i]
+// integrateRows builds an integral table in a single pass: every cell is
+// the running sum of its row plus the cell above it.
+func integrateRows[T ~uint16 | ~uint32 | ~uint64](w, h int, colors []T, square bool) []uint64 {
+	result := make([]uint64, w*h)
+	for y := 0; y < h; y++ {
+		row := colors[y*w : (y+1)*w]
+		out := result[y*w : (y+1)*w]
+		running := uint64(0)
+		for x, v := range row {
+			if square {
+				running += uint64(v) * uint64(v)
+			} else {
+				running += uint64(v)
+			}
+			out[x] = running
+		}
+		if y > 0 {
+			above := result[(y-1)*w : y*w]
+			for x := range out {
+				out[x] += above[x]
+			}
+		}
 	}
-	return result, nil
+	return result
 }
@@ -1,13 +1,18 @@
 package imaging_test
 
 import (
+	"errors"
 	"fmt"
-	"image/png"
+	"image"
+	"image/draw"
+	"math"
 	"math/rand"
 	"os"
 	"testing"
 	"time"
 
+	c "image/color"
+
 	"github.com/ramadoka/penguin-logic/pkg/color"
 	"github.com/ramadoka/penguin-logic/pkg/euclidean"
 	"github.com/ramadoka/penguin-logic/pkg/imaging"
@@ -72,10 +77,11 @@ func TestExtract(t *testing.T) {
 	colors := []color.Channel{color.ChannelRed, color.ChannelGreen, color.ChannelBlue}
 	for n, color := range colors {
 		outPath := fmt.Sprintf("%s/%d.png", outputDir, n)
-		outF, _ := os.Create(outPath)
-		extracted := i.Extract(color)
-		png.Encode(outF, extracted)
-		outF.Close()
+		extracted, err := i.Extract(color)
+		if err != nil {
+			t.Fatal(err)
+		}
+		imaging.SaveImage(extracted, outPath, imaging.SaveOptions{})
 	}
 	t.Error("Extraction test not implemented")
 }
@@ -91,7 +97,10 @@ func TestIntegral(t *testing.T) {
 	for tc, bound := range tcs() {
 		for _, channel := range colors {
 			for n, p := range patterns {
-				feat := integral.ApplyFeat(channel, bound, p)
+				feat, err := integral.ApplyFeat(channel, bound, p)
+				if err != nil {
+					t.Fatal(err)
+				}
 				fmt.Printf("tc #%d. (%s) (Feat#%d) channel %s: %d\n", tc, bound, n, channel, feat)
 			}
 			fmt.Println()
@@ -139,9 +148,11 @@ func TestFixed1(t *testing.T) {
 		// 	t.Error(err)
 		// }
 		for _, channel := range color.Channels() {
-			extracted := cropped.Extract(channel)
-			outF, _ := os.Create(fmt.Sprintf("%s_%s.png", outPath, channel))
-			png.Encode(outF, extracted)
+			extracted, err := cropped.Extract(channel)
+			if err != nil {
+				t.Fatal(err)
+			}
+			imaging.SaveImage(extracted, fmt.Sprintf("%s_%s.png", outPath, channel), imaging.SaveOptions{})
 		}
 		// t.Errorf("%d: Guessed Right for %s", idx, tc)
 		t.Errorf("%d. Guess for %s (%+v)", idx, tc, values)
@@ -165,7 +176,10 @@ func TestFixed(t *testing.T) {
 	// tcsAll = append(tcsAll, tcs()...)
 	for idx, tc := range tcsAll {
 		// values, guess := integral.Guess(tc)
-		recenteredBound := integral.BoundRecenter(color.ChannelGray, tc, 5)
+		recenteredBound, err := integral.BoundRecenter(color.ChannelGray, tc, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
 		fmt.Printf("%d. Center of Mass for %s\n", idx, tc)
 		fmt.Printf("OriginalCenter: %v - CenterOfMass: %v\n", tc.Center(), recenteredBound.Center())
 		outName := fmt.Sprintf("%d", idx)
@@ -175,9 +189,11 @@ func TestFixed(t *testing.T) {
 		// 	t.Errorf("%d failed to guess for %+v => %+v", idx, tc, values)
 		// }
 		for _, channel := range color.Channels() {
-			extracted := cropped.Extract(channel)
-			outF, _ := os.Create(fmt.Sprintf("%s_%s.png", outPath, channel))
-			png.Encode(outF, extracted)
+			extracted, err := cropped.Extract(channel)
+			if err != nil {
+				t.Fatal(err)
+			}
+			imaging.SaveImage(extracted, fmt.Sprintf("%s_%s.png", outPath, channel), imaging.SaveOptions{})
 		}
 		// // t.Errorf("%d: Guessed Right for %s", idx, tc)
 		// t.Errorf("%d. Guess for %s (%+v)", idx, tc, values)
@@ -199,7 +215,10 @@ func TestRandom(t *testing.T) {
 		b := randomBound(130, 130)
 		_, yesno := integral.Guess(b)
 		if yesno {
-			rebound := integral.BoundRecenter(color.ChannelGray, b, 5)
+			rebound, err := integral.BoundRecenter(color.ChannelGray, b, 5)
+			if err != nil {
+				t.Fatal(err)
+			}
 			outName := fmt.Sprintf("YES-%d-%v", idx, rebound.Center())
 			outPath := fmt.Sprintf("%s/%s", outDir, outName)
 			cropped := i.Invert().Crop(rebound)
@@ -215,3 +234,305 @@ func TestRandom(t *testing.T) {
 		}
 	}
 }
+
+func TestMeanVariance(t *testing.T) {
+	src := randomOpaque(t, 7, 5, 1)
+	integral, err := imaging.New(src).Integral()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range []euclidean.IBound{
+		euclidean.Bound(euclidean.P2(0, 0), euclidean.P2(7, 5)),
+		euclidean.Bound(euclidean.P2(2, 1), euclidean.P2(5, 4)),
+		euclidean.Bound(euclidean.P2(3, 2), euclidean.P2(4, 3)),
+		euclidean.Bound(euclidean.P2(-2, -2), euclidean.P2(2, 2)),
+	} {
+		values := []float64{}
+		for y := max(b.Top(), 0); y < b.Bottom(); y++ {
+			for x := max(b.Left(), 0); x < b.Right(); x++ {
+				r, _, _, _ := src.At(int(x), int(y)).RGBA()
+				values = append(values, float64(r))
+			}
+		}
+		mean, variance := 0.0, 0.0
+		for _, v := range values {
+			mean += v / float64(len(values))
+		}
+		for _, v := range values {
+			variance += (v - mean) * (v - mean) / float64(len(values))
+		}
+		if got, err := integral.Mean(color.ChannelRed, b); err != nil || math.Abs(got-mean) > 1e-6 {
+			t.Errorf("%s: expected mean %f, got %f", b.ToString(), mean, got)
+		}
+		if got, err := integral.Variance(color.ChannelRed, b); err != nil || math.Abs(got-variance) > 1e-3*max(variance, 1) {
+			t.Errorf("%s: expected variance %f, got %f", b.ToString(), variance, got)
+		}
+		if got, err := integral.StdDev(color.ChannelRed, b); err != nil || math.Abs(got-math.Sqrt(variance)) > 1e-3*max(math.Sqrt(variance), 1) {
+			t.Errorf("%s: expected standard deviation %f, got %f", b.ToString(), math.Sqrt(variance), got)
+		}
+	}
+}
+
+func TestCalculateDoesNotOverflow(t *testing.T) {
+	// 300 x 300 white pixels sum to 300 * 300 * 0xffff, above 2^32
+	src := image.NewRGBA(image.Rect(0, 0, 300, 300))
+	draw.Draw(src, src.Bounds(), image.White, image.Point{}, draw.Src)
+	integral, err := imaging.New(src).Integral()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range []euclidean.IBound{
+		euclidean.Bound(euclidean.P2(0, 0), euclidean.P2(300, 300)),
+		euclidean.Bound(euclidean.P2(10, 20), euclidean.P2(290, 280)),
+	} {
+		want := int64(b.Width()) * int64(b.Height()) * 0xffff
+		for _, channel := range []color.Channel{color.ChannelRed, color.ChannelGreen, color.ChannelBlue, color.ChannelGray} {
+			if got, err := integral.Calculate(channel, b); err != nil || got != want {
+				t.Errorf("%s %s: expected %d, got %d", channel, b.ToString(), want, got)
+			}
+		}
+	}
+}
+
+func TestIntegralMatchesSequential(t *testing.T) {
+	img, err := imaging.Load(inputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parallel, err := img.Integral()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sequential, err := imaging.IntegralSequential(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, want := imaging.IntegralTables(parallel), imaging.IntegralTables(sequential)
+	for table := range want {
+		if len(got[table]) != len(want[table]) {
+			t.Fatalf("table %d: expected %d values, got %d", table, len(want[table]), len(got[table]))
+		}
+		for idx := range want[table] {
+			if got[table][idx] != want[table][idx] {
+				t.Fatalf("table %d at %d: expected %d, got %d", table, idx, want[table][idx], got[table][idx])
+			}
+		}
+	}
+}
+
+func TestDerivedChannels(t *testing.T) {
+	src := randomOpaque(t, 6, 4, 2)
+	img := imaging.New(src)
+	integral, err := img.Integral()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := euclidean.Bound(euclidean.P2(1, 1), euclidean.P2(5, 3))
+	for _, channel := range color.DerivedChannels() {
+		want, squares := int64(0), 0.0
+		for y := b.Top(); y < b.Bottom(); y++ {
+			for x := b.Left(); x < b.Right(); x++ {
+				cr, cg, cb, _ := src.At(int(x), int(y)).RGBA()
+				v := color.Derive(channel, uint16(cr), uint16(cg), uint16(cb))
+				want += int64(v)
+				squares += float64(v) * float64(v)
+			}
+		}
+		if got, err := integral.Calculate(channel, b); err != nil || got != want {
+			t.Errorf("%s: expected %d, got %d", channel, want, got)
+		}
+		mean := float64(want) / 8
+		if got, err := integral.Variance(channel, b); err != nil || math.Abs(got-(squares/8-mean*mean)) > 1e-3*max(got, 1) {
+			t.Errorf("%s: expected variance %f, got %f", channel, squares/8-mean*mean, got)
+		}
+		drawn, err := img.Extract(channel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		extracted := drawn.At(2, 1).(c.RGBA)
+		cr, cg, cb, _ := src.At(2, 1).RGBA()
+		v := color.To8(color.Derive(channel, uint16(cr), uint16(cg), uint16(cb)))
+		if extracted.R != v || extracted.G != v || extracted.B != v || extracted.A != color.Max8 {
+			t.Errorf("%s: expected a gray of %d, got %+v", channel, v, extracted)
+		}
+	}
+}
+
+// A ring of reds on both sides of 0 degrees averages to cyan on the hue
+// channel, and back to red through the hue-x and hue-y ones.
+func TestMeanHueAcrossRed(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 8, 4))
+	draw.Draw(src, image.Rect(0, 0, 4, 4), image.NewUniform(c.RGBA{255, 9, 0, 255}), image.Point{}, draw.Src)
+	draw.Draw(src, image.Rect(4, 0, 8, 4), image.NewUniform(c.RGBA{255, 0, 9, 255}), image.Point{}, draw.Src)
+	integral, err := imaging.New(src).Integral()
+	if err != nil {
+		t.Fatal(err)
+	}
+	whole := euclidean.Bound(euclidean.P2(0, 0), euclidean.P2(8, 4))
+	linear, err := integral.Mean(color.ChannelHue, whole)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if degrees := linear / color.Max * 360; math.Abs(degrees-180) > 1 {
+		t.Errorf("expected the linear hue mean at 180 degrees, got %f", degrees)
+	}
+	x, err := integral.Mean(color.ChannelHueX, whole)
+	if err != nil {
+		t.Fatal(err)
+	}
+	y, err := integral.Mean(color.ChannelHueY, whole)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hue, strength := color.MeanHue(x, y)
+	if math.Min(hue, 360-hue) > 0.1 || strength < 0.99 {
+		t.Errorf("expected a saturated red, got a hue of %f at %f", hue, strength)
+	}
+}
+
+func TestSubImageIntegral(t *testing.T) {
+	src := randomOpaque(t, 9, 7, 4)
+	img := imaging.New(src)
+	whole, err := img.Integral()
+	if err != nil {
+		t.Fatal(err)
+	}
+	view, err := img.SubImage(euclidean.Bound(euclidean.P2(2, 1), euclidean.P2(8, 6))).Integral()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if view.Width() != 6 || view.Height() != 5 {
+		t.Fatalf("expected a 6 x 5 integral, got %d x %d", view.Width(), view.Height())
+	}
+	for _, tc := range []struct {
+		inView, inImage euclidean.IBound
+	}{
+		{euclidean.Bound(euclidean.P2(0, 0), euclidean.P2(6, 5)), euclidean.Bound(euclidean.P2(2, 1), euclidean.P2(8, 6))},
+		{euclidean.Bound(euclidean.P2(1, 2), euclidean.P2(4, 3)), euclidean.Bound(euclidean.P2(3, 3), euclidean.P2(6, 4))},
+		// clipped to the view, not to the image
+		{euclidean.Bound(euclidean.P2(-1, -1), euclidean.P2(3, 3)), euclidean.Bound(euclidean.P2(2, 1), euclidean.P2(5, 4))},
+		{euclidean.Bound(euclidean.P2(4, 3), euclidean.P2(9, 9)), euclidean.Bound(euclidean.P2(6, 4), euclidean.P2(8, 6))},
+	} {
+		for _, channel := range []color.Channel{color.ChannelRed, color.ChannelGray, color.ChannelHue} {
+			want, err := whole.Calculate(channel, tc.inImage)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, err := view.Calculate(channel, tc.inView); err != nil || got != want {
+				t.Errorf("%s %s: expected %d, got %d (%v)", channel, tc.inView.ToString(), want, got, err)
+			}
+		}
+	}
+
+	// adjacent bounds share no pixel
+	left := euclidean.Bound(euclidean.P2(0, 0), euclidean.P2(4, 7))
+	right := euclidean.Bound(euclidean.P2(4, 0), euclidean.P2(9, 7))
+	full := euclidean.Bound(euclidean.P2(0, 0), euclidean.P2(9, 7))
+	sums := [3]int64{}
+	for idx, b := range []euclidean.IBound{left, right, full} {
+		if sums[idx], err = whole.Calculate(color.ChannelBlue, b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if sums[0]+sums[1] != sums[2] {
+		t.Errorf("expected %d + %d to make %d", sums[0], sums[1], sums[2])
+	}
+	empty := euclidean.Bound(euclidean.P2(3, 3), euclidean.P2(3, 5))
+	if got, err := whole.Calculate(color.ChannelBlue, empty); err != nil || got != 0 {
+		t.Errorf("expected nothing in an empty bound, got %d (%v)", got, err)
+	}
+}
+
+// redMinusBlue is registered once for the package, the registry refusing
+// to register a name twice when the tests run again.
+var redMinusBlue, registerErr = color.Register("test-red-minus-blue", func(r, _, b, _ uint16) uint16 {
+	return uint16((int32(r) - int32(b) + color.Max) / 2)
+})
+
+func TestRegisteredChannels(t *testing.T) {
+	if registerErr != nil {
+		t.Fatal(registerErr)
+	}
+	if channel, err := color.Lookup("test-red-minus-blue"); err != nil || channel != redMinusBlue {
+		t.Errorf("expected %s, got %s (%v)", redMinusBlue, channel, err)
+	}
+	if _, err := color.Register("test-red-minus-blue", func(r, _, _, _ uint16) uint16 { return r }); err == nil {
+		t.Error("expected an error registering a name twice")
+	}
+
+	src := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	draw.Draw(src, image.Rect(0, 0, 2, 2), &image.Uniform{c.RGBA{R: 255, A: 255}}, image.Point{}, draw.Src)
+	draw.Draw(src, image.Rect(2, 0, 4, 2), &image.Uniform{c.RGBA{B: 255, A: 255}}, image.Point{}, draw.Src)
+	img := imaging.New(src)
+	integral, err := img.Integral()
+	if err != nil {
+		t.Fatal(err)
+	}
+	left := euclidean.Bound(euclidean.P2(0, 0), euclidean.P2(2, 2))
+	right := euclidean.Bound(euclidean.P2(2, 0), euclidean.P2(4, 2))
+	if got, err := integral.Calculate(redMinusBlue, left); err != nil || got != 4*color.Max {
+		t.Errorf("expected %d on the red half, got %d (%v)", 4*color.Max, got, err)
+	}
+	if got, err := integral.Mean(redMinusBlue, right); err != nil || got != 0 {
+		t.Errorf("expected a mean of 0 on the blue half, got %f (%v)", got, err)
+	}
+	whole := euclidean.Bound(euclidean.P2(0, 0), euclidean.P2(4, 2))
+	if _, err := integral.ApplyFeat(redMinusBlue, whole, imaging.FeatHorizontal()); err != nil {
+		t.Error(err)
+	}
+
+	unknown := color.Channel(1 << 20)
+	if _, err := integral.Calculate(unknown, whole); !errors.Is(err, color.ErrUnknownChannel) {
+		t.Errorf("Calculate: expected ErrUnknownChannel, got %v", err)
+	}
+	if _, err := integral.ApplyFeat(unknown, whole, imaging.FeatHorizontal()); !errors.Is(err, color.ErrUnknownChannel) {
+		t.Errorf("ApplyFeat: expected ErrUnknownChannel, got %v", err)
+	}
+	if _, err := integral.Mean(unknown, whole); !errors.Is(err, color.ErrUnknownChannel) {
+		t.Errorf("Mean: expected ErrUnknownChannel, got %v", err)
+	}
+	if _, err := img.Extract(unknown); !errors.Is(err, color.ErrUnknownChannel) {
+		t.Errorf("Extract: expected ErrUnknownChannel, got %v", err)
+	}
+	scan := imaging.Scanner(2, 1, 1).Scan(integral, []color.Channel{unknown}, imaging.FeatHorizontal(), func(imaging.Candidate) bool {
+		t.Error("expected no candidate on an unknown channel")
+		return true
+	})
+	if !errors.Is(scan, color.ErrUnknownChannel) {
+		t.Errorf("Scan: expected ErrUnknownChannel, got %v", scan)
+	}
+}
+
+func BenchmarkLoad(b *testing.B) {
+	for range b.N {
+		if _, err := imaging.Load(inputPath); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkIntegral(b *testing.B) {
+	img, err := imaging.Load(inputPath)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for range b.N {
+		if _, err := imaging.BuildIntegral(img); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkIntegralSequential(b *testing.B) {
+	img, err := imaging.Load(inputPath)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for range b.N {
+		if _, err := imaging.IntegralSequential(img); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package imaging
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/ramadoka/penguin-logic/pkg/euclidean"
+)
+
+// Lattice is the regular grid depot items are laid on. Cell (0, 0) is the
+// top-left one and is centered on (OffsetX, OffsetY).
+type Lattice struct {
+	Rows       int
+	Cols       int
+	PitchX     float64
+	PitchY     float64
+	OffsetX    float64
+	OffsetY    float64
+	CellWidth  euclidean.W
+	CellHeight euclidean.H
+}
+
+type LatticeCell struct {
+	Row int
+	Col int
+}
+
+// Placement is a lattice cell with the detection snapped to it, or with a
+// nil Detection and the predicted bound when nothing was detected there.
+// CellBound is the bound the lattice predicts for the cell either way:
+// better centered on the item than a detection window, it is the one to
+// read the contents of the cell in.
+type Placement struct {
+	Cell      LatticeCell
+	Bound     euclidean.IBound
+	CellBound euclidean.IBound
+	Detection *Detection
+}
+
+// FitLattice estimates the lattice from noisy detections: centers are
+// clustered per axis, the pitch and offset are the least squares fit of
+// the cluster centers, and the cell size is the median detection size.
+func FitLattice(detections []Detection) (Lattice, error) {
+	if len(detections) == 0 {
+		return Lattice{}, errors.New("cannot fit a lattice without detections")
+	}
+	xs := make([]float64, len(detections))
+	ys := make([]float64, len(detections))
+	ws := make([]float64, len(detections))
+	hs := make([]float64, len(detections))
+	for idx, d := range detections {
+		center := d.Bound.CenterF()
+		xs[idx] = center.X
+		ys[idx] = center.Y
+		ws[idx] = float64(d.Bound.Width())
+		hs[idx] = float64(d.Bound.Height())
+	}
+	cellW, cellH := median(ws), median(hs)
+	if cellW <= 0 || cellH <= 0 {
+		return Lattice{}, errors.New("cannot fit a lattice on empty bounds")
+	}
+	offsetX, pitchX, cols := fitAxis(xs, cellW)
+	offsetY, pitchY, rows := fitAxis(ys, cellH)
+	return Lattice{
+		Rows:       rows,
+		Cols:       cols,
+		PitchX:     pitchX,
+		PitchY:     pitchY,
+		OffsetX:    offsetX,
+		OffsetY:    offsetY,
+		CellWidth:  euclidean.W(math.Round(cellW)),
+		CellHeight: euclidean.H(math.Round(cellH)),
+	}, nil
+}
+
+func (l Lattice) Cells() []LatticeCell {
+	cells := make([]LatticeCell, 0, l.Rows*l.Cols)
+	for row := 0; row < l.Rows; row++ {
+		for col := 0; col < l.Cols; col++ {
+			cells = append(cells, LatticeCell{Row: row, Col: col})
+		}
+	}
+	return cells
+}
+
+func (l Lattice) Center(cell LatticeCell) euclidean.Point {
+	x := l.OffsetX + float64(cell.Col)*l.PitchX
+	y := l.OffsetY + float64(cell.Row)*l.PitchY
+	return euclidean.P2(euclidean.X(math.Round(x)), euclidean.Y(math.Round(y)))
+}
+
+// Bound is the predicted bound of a cell, sized like the median detection.
+func (l Lattice) Bound(cell LatticeCell) euclidean.IBound {
+	center := l.Center(cell)
+	topLeft := euclidean.P2(center.X.Sub(l.CellWidth/2), center.Y.Sub(l.CellHeight/2))
+	return euclidean.BoundAt(topLeft, l.CellWidth, l.CellHeight)
+}
+
+// Snap returns the cell closest to the center of b, and false when b is
+// outside the lattice or farther than a third of the pitch from that cell.
+func (l Lattice) Snap(b euclidean.IBound) (LatticeCell, bool) {
+	center := b.CenterF()
+	col, dx := snapAxis(center.X, l.OffsetX, l.PitchX)
+	row, dy := snapAxis(center.Y, l.OffsetY, l.PitchY)
+	cell := LatticeCell{Row: row, Col: col}
+	if row < 0 || row >= l.Rows || col < 0 || col >= l.Cols {
+		return cell, false
+	}
+	return cell, math.Abs(dx) <= l.PitchX/3 && math.Abs(dy) <= l.PitchY/3
+}
+
+// Place snaps every detection to its cell, keeping the highest scoring one
+// when several land on the same cell, and predicts the bound of the cells
+// nothing landed on. Placements are returned row by row.
+func (l Lattice) Place(detections []Detection) []Placement {
+	best := map[LatticeCell]int{}
+	for idx, d := range detections {
+		cell, ok := l.Snap(d.Bound)
+		if !ok {
+			continue
+		}
+		if prev, exists := best[cell]; exists && detections[prev].Score >= d.Score {
+			continue
+		}
+		best[cell] = idx
+	}
+	placements := make([]Placement, 0, l.Rows*l.Cols)
+	for _, cell := range l.Cells() {
+		predicted := l.Bound(cell)
+		idx, exists := best[cell]
+		if !exists {
+			placements = append(placements, Placement{Cell: cell, Bound: predicted, CellBound: predicted})
+			continue
+		}
+		d := detections[idx]
+		placements = append(placements, Placement{Cell: cell, Bound: d.Bound, CellBound: predicted, Detection: &d})
+	}
+	return placements
+}
+
+// fitAxis clusters the coordinates of one axis, values closer than half a
+// cell belonging to the same row or column, and fits center = offset +
+// index * pitch through the cluster medians. When several clusters land on
+// the same index, only the most populated one is fitted.
+func fitAxis(values []float64, size float64) (offset, pitch float64, count int) {
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	centers := []float64{}
+	members := []int{}
+	start := 0
+	for idx := 1; idx <= len(sorted); idx++ {
+		if idx == len(sorted) || sorted[idx]-sorted[idx-1] > size/2 {
+			centers = append(centers, median(sorted[start:idx]))
+			members = append(members, idx-start)
+			start = idx
+		}
+	}
+	if len(centers) == 1 {
+		return centers[0], size, 1
+	}
+
+	gaps := make([]float64, len(centers)-1)
+	for idx := range gaps {
+		gaps[idx] = centers[idx+1] - centers[idx]
+	}
+	pitch = median(gaps)
+	best := map[int]int{}
+	for idx, c := range centers {
+		k := int(math.Round((c - centers[0]) / pitch))
+		if prev, exists := best[k]; exists && members[prev] >= members[idx] {
+			continue
+		}
+		best[k] = idx
+	}
+
+	indices := make([]int, 0, len(best))
+	for k := range best {
+		indices = append(indices, k)
+	}
+	sort.Ints(indices)
+
+	n := float64(len(indices))
+	sumK, sumC, sumKK, sumKC := 0.0, 0.0, 0.0, 0.0
+	for _, k := range indices {
+		c := centers[best[k]]
+		sumK += float64(k)
+		sumC += c
+		sumKK += float64(k * k)
+		sumKC += float64(k) * c
+	}
+	if denom := n*sumKK - sumK*sumK; denom != 0 {
+		pitch = (n*sumKC - sumK*sumC) / denom
+	}
+	offset = (sumC - pitch*sumK) / n
+	return offset, pitch, indices[len(indices)-1] + 1
+}
+
+func snapAxis(v, offset, pitch float64) (int, float64) {
+	if pitch <= 0 {
+		return 0, v - offset
+	}
+	index := math.Round((v - offset) / pitch)
+	return int(index), v - (offset + index*pitch)
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
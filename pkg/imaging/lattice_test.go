@@ -0,0 +1,71 @@
+package imaging_test
+
+import (
+	"testing"
+
+	"github.com/ramadoka/penguin-logic/pkg/euclidean"
+	"github.com/ramadoka/penguin-logic/pkg/imaging"
+)
+
+func TestFitLattice(t *testing.T) {
+	// 3 x 8 cells, 146 x 180 px apart, with a few pixels of noise and two
+	// cells left undetected.
+	noise := []int{0, 3, -4, 2, -1, 5, -3, 1}
+	detections := []imaging.Detection{}
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 8; col++ {
+			if (row == 1 && col == 3) || (row == 2 && col == 7) {
+				continue
+			}
+			x := euclidean.X(50 + col*146 + noise[(row+col)%len(noise)])
+			y := euclidean.Y(30 + row*180 + noise[(row*3+col)%len(noise)])
+			b := euclidean.Bound(euclidean.P2(x, y), euclidean.P2(x+130, y+130))
+			detections = append(detections, imaging.Detection{Bound: b, Score: 0.5})
+		}
+	}
+	// a duplicate of cell (0, 0) and an item between two cells
+	detections = append(detections,
+		imaging.Detection{Bound: euclidean.Bound(euclidean.P2(52, 31), euclidean.P2(182, 161)), Score: 0.9},
+		imaging.Detection{Bound: euclidean.Bound(euclidean.P2(123, 30), euclidean.P2(253, 160)), Score: 0.9},
+	)
+
+	lattice, err := imaging.FitLattice(detections)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lattice.Rows != 3 || lattice.Cols != 8 {
+		t.Fatalf("expected a 3 x 8 lattice, got %d x %d", lattice.Rows, lattice.Cols)
+	}
+	if lattice.PitchX < 144 || lattice.PitchX > 148 || lattice.PitchY < 178 || lattice.PitchY > 182 {
+		t.Errorf("unexpected pitch %f x %f", lattice.PitchX, lattice.PitchY)
+	}
+	if lattice.CellWidth != 130 || lattice.CellHeight != 130 {
+		t.Errorf("unexpected cell size %d x %d", lattice.CellWidth, lattice.CellHeight)
+	}
+
+	placements := lattice.Place(detections)
+	if len(placements) != 24 {
+		t.Fatalf("expected 24 placements, got %d", len(placements))
+	}
+	for _, p := range placements {
+		missing := (p.Cell == imaging.LatticeCell{Row: 1, Col: 3}) || (p.Cell == imaging.LatticeCell{Row: 2, Col: 7})
+		if missing != (p.Detection == nil) {
+			t.Errorf("cell %+v: expected missing=%v", p.Cell, missing)
+		}
+	}
+	if placements[0].Detection.Score != 0.9 {
+		t.Errorf("expected the best duplicate on cell (0, 0), got %+v", placements[0].Detection)
+	}
+	for _, p := range placements {
+		if p.CellBound.ToString() != lattice.Bound(p.Cell).ToString() {
+			t.Errorf("cell %+v: expected the cell bound %s, got %s", p.Cell, lattice.Bound(p.Cell).ToString(), p.CellBound.ToString())
+		}
+		if p.Detection != nil && p.Bound != p.Detection.Bound {
+			t.Errorf("cell %+v: expected the detected window %s, got %s", p.Cell, p.Detection.Bound.ToString(), p.Bound.ToString())
+		}
+	}
+	predicted := placements[11].Bound.Center()
+	if predicted.X < 554-4 || predicted.X > 554+4 || predicted.Y < 275-4 || predicted.Y > 275+4 {
+		t.Errorf("unexpected predicted center for cell (1, 3): %s", predicted.ToString())
+	}
+}
@@ -0,0 +1,200 @@
+package imaging
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ramadoka/penguin-logic/pkg/color"
+	"github.com/ramadoka/penguin-logic/pkg/euclidean"
+)
+
+const (
+	// iconGrid x iconGrid is the grid of mean colors an icon is reduced to.
+	iconGrid = 12
+	// iconRadius and iconPill, in cell sizes from the cell center, mask the
+	// grid cells outside of the ring and the ones the expiry pill covers.
+	iconRadius = 0.42
+	iconPill   = 0.38
+	// iconKept is the share of the closest grid cells a score is computed
+	// on, the others being taken as covered by the count badge.
+	iconKept = 0.8
+	// iconRunnersUp is how many candidates are reported after the best one.
+	iconRunnersUp = 3
+)
+
+// IconScore is the similarity, in [0, 1], of a cell with a library item.
+type IconScore struct {
+	ID    string
+	Score float64
+}
+
+// IconMatch is the library item closest to a cell, followed by the next
+// closest ones, best first.
+type IconMatch struct {
+	IconScore
+	RunnersUp []IconScore
+}
+
+type IIconLibrary interface {
+	IDs() []string
+	Match(img Image, bound euclidean.IBound) (IconMatch, error)
+}
+
+type iconLibrary struct {
+	ids         []string
+	descriptors [][]iconCell
+}
+
+// iconCell is the mean color of a grid cell, on [0, 1], and whether it is
+// part of the icon.
+type iconCell struct {
+	rgb  [3]float64
+	used bool
+}
+
+// IconLibrary builds a library from reference icons keyed by item ID. A
+// reference is a crop of a depot cell, framed like a detection, of any size.
+func IconLibrary(icons map[string]Image) (IIconLibrary, error) {
+	ids := make([]string, 0, len(icons))
+	for id := range icons {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	l := &iconLibrary{ids: ids, descriptors: make([][]iconCell, len(ids))}
+	for idx, id := range ids {
+		icon := icons[id]
+		integral, err := icon.Integral()
+		if err != nil {
+			return nil, fmt.Errorf("icon %s: %w", id, err)
+		}
+		frame := euclidean.BoundAt(euclidean.P2(0, 0), icon.Width(), icon.Height())
+		if l.descriptors[idx], err = describeIcon(integral, frame); err != nil {
+			return nil, fmt.Errorf("icon %s: %w", id, err)
+		}
+	}
+	return l, nil
+}
+
+// LoadIconLibrary loads every PNG of dir, the file name without its
+// extension being the item ID.
+func LoadIconLibrary(dir string) (IIconLibrary, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	icons := map[string]Image{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".png") {
+			continue
+		}
+		img, err := Load(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("icon %s: %w", entry.Name(), err)
+		}
+		icons[strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))] = img
+	}
+	if len(icons) == 0 {
+		return nil, fmt.Errorf("no icon found in %s", dir)
+	}
+	return IconLibrary(icons)
+}
+
+func (l *iconLibrary) IDs() []string {
+	ids := make([]string, len(l.ids))
+	copy(ids, l.ids)
+	return ids
+}
+
+// Match compares the cell in bound with every icon of the library.
+func (l *iconLibrary) Match(img Image, bound euclidean.IBound) (IconMatch, error) {
+	if len(l.ids) == 0 {
+		return IconMatch{}, errors.New("empty icon library")
+	}
+	integral, err := img.Integral()
+	if err != nil {
+		return IconMatch{}, err
+	}
+	cell, err := describeIcon(integral, bound)
+	if err != nil {
+		return IconMatch{}, err
+	}
+	scores := make([]IconScore, len(l.ids))
+	for idx, id := range l.ids {
+		scores[idx] = IconScore{ID: id, Score: compareIcons(cell, l.descriptors[idx])}
+	}
+	sort.SliceStable(scores, func(i, j int) bool {
+		return scores[i].Score > scores[j].Score
+	})
+	return IconMatch{
+		IconScore: scores[0],
+		RunnersUp: scores[1:min(len(scores), 1+iconRunnersUp)],
+	}, nil
+}
+
+// describeIcon reduces the icon in bound to the mean color of every cell of
+// the icon grid.
+func describeIcon(integral IntegralImage, bound euclidean.IBound) ([]iconCell, error) {
+	w, h := int(bound.Width()), int(bound.Height())
+	if w < iconGrid || h < iconGrid {
+		return nil, fmt.Errorf("icon smaller than %dx%d", iconGrid, iconGrid)
+	}
+	channels := color.Channels()
+	cells := make([]iconCell, 0, iconGrid*iconGrid)
+	for row := 0; row < iconGrid; row++ {
+		y0, y1 := row*h/iconGrid, (row+1)*h/iconGrid
+		for col := 0; col < iconGrid; col++ {
+			x0, x1 := col*w/iconGrid, (col+1)*w/iconGrid
+			dx := (float64(x0+x1)/2 - float64(w)/2) / float64(w)
+			dy := (float64(y0+y1)/2 - float64(h)/2) / float64(h)
+			cell := iconCell{used: math.Hypot(dx, dy) <= iconRadius && dy < iconPill}
+			b := euclidean.Bound(
+				euclidean.P2(bound.Left()+euclidean.X(x0), bound.Top()+euclidean.Y(y0)),
+				euclidean.P2(bound.Left()+euclidean.X(x1), bound.Top()+euclidean.Y(y1)),
+			)
+			for idx, channel := range channels {
+				// Mean only counts the pixels inside the image, for icons
+				// cut by its edge
+				mean, err := integral.Mean(channel, b)
+				if err != nil {
+					return nil, err
+				}
+				cell.rgb[idx] = mean / color.Max
+			}
+			cells = append(cells, cell)
+		}
+	}
+	return cells, nil
+}
+
+// compareIcons is one minus the mean color distance of the closest iconKept
+// share of the grid cells, so that an overlay on part of the icon does not
+// weigh on the score.
+func compareIcons(a, b []iconCell) float64 {
+	distances := []float64{}
+	for idx := range a {
+		if !a[idx].used || !b[idx].used {
+			continue
+		}
+		d := 0.0
+		for channel := range a[idx].rgb {
+			diff := a[idx].rgb[channel] - b[idx].rgb[channel]
+			d += diff * diff
+		}
+		distances = append(distances, math.Sqrt(d/3))
+	}
+	if len(distances) == 0 {
+		return 0
+	}
+	sort.Float64s(distances)
+	kept := distances[:max(1, int(math.Round(iconKept*float64(len(distances)))))]
+	sum := 0.0
+	for _, d := range kept {
+		sum += d
+	}
+	return 1 - sum/float64(len(kept))
+}
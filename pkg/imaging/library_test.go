@@ -0,0 +1,125 @@
+package imaging_test
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	c "image/color"
+
+	"github.com/ramadoka/penguin-logic/pkg/euclidean"
+	"github.com/ramadoka/penguin-logic/pkg/imaging"
+)
+
+// sameItem maps the cells holding an item already shown in another cell to
+// that cell: (1, 4) and (2, 4) only differ by their count and expiry pill.
+var sameItem = map[imaging.LatticeCell]imaging.LatticeCell{
+	{Row: 2, Col: 4}: {Row: 1, Col: 4},
+}
+
+// writeIcons saves the cells of the depot, shifted by a few pixels and with
+// their count badge painted over, as reference icons named after the cells.
+func writeIcons(t *testing.T, placements []imaging.Placement) string {
+	t.Helper()
+	f, err := os.Open(inputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	src, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	for _, p := range placements {
+		if _, duplicate := sameItem[p.Cell]; duplicate {
+			continue
+		}
+		b := p.CellBound
+		rect := image.Rect(int(b.Left())+3, int(b.Top())-2, int(b.Right())+3, int(b.Bottom())-2)
+		icon := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+		draw.Draw(icon, icon.Bounds(), src, rect.Min, draw.Src)
+		badge := image.Rect(rect.Dx()/2, rect.Dy()*2/3, rect.Dx()*9/10, rect.Dy()*9/10)
+		draw.Draw(icon, badge, image.NewUniform(c.Black), image.Point{}, draw.Src)
+
+		out, err := os.Create(filepath.Join(dir, cellID(p)+".png"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := png.Encode(out, icon); err != nil {
+			t.Fatal(err)
+		}
+		out.Close()
+	}
+	return dir
+}
+
+func cellID(p imaging.Placement) string {
+	cell := p.Cell
+	if original, duplicate := sameItem[cell]; duplicate {
+		cell = original
+	}
+	return fmt.Sprintf("item-%d-%d", cell.Row, cell.Col)
+}
+
+func TestIconLibraryMatch(t *testing.T) {
+	img, placements := depot(t)
+	library, err := imaging.LoadIconLibrary(writeIcons(t, placements))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := len(placements) - len(sameItem); len(library.IDs()) != want {
+		t.Fatalf("expected %d icons, got %d", want, len(library.IDs()))
+	}
+	for _, p := range placements {
+		match, err := library.Match(img, p.CellBound)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if match.ID != cellID(p) {
+			t.Errorf("cell %+v: expected %s, got %s (%.3f)", p.Cell, cellID(p), match.ID, match.Score)
+		}
+		if len(match.RunnersUp) != 3 {
+			t.Fatalf("cell %+v: expected 3 runners-up, got %d", p.Cell, len(match.RunnersUp))
+		}
+		if match.RunnersUp[0].Score > match.Score {
+			t.Errorf("cell %+v: runner-up %s scores above the best match", p.Cell, match.RunnersUp[0].ID)
+		}
+	}
+}
+
+func TestLoadIconLibraryEmpty(t *testing.T) {
+	if _, err := imaging.LoadIconLibrary(t.TempDir()); err == nil {
+		t.Error("expected an error on a directory without icons")
+	}
+}
+
+// An icon cut by the edge of the screenshot is described by the pixels of
+// every grid cell lying in the image, not darkened by the ones outside.
+func TestIconColorsClamped(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 50, 50))
+	draw.Draw(src, src.Bounds(), image.White, image.Point{}, draw.Src)
+	integral, err := imaging.New(src).Integral()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// grid cells of 5 pixels, the first row and column lying outside and
+	// the second ones straddling the edge
+	colors, err := imaging.IconColors(integral, euclidean.Bound(euclidean.P2(-6, -6), euclidean.P2(54, 54)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for idx, rgb := range colors {
+		want := 1.0
+		if idx < 12 || idx%12 == 0 {
+			want = 0
+		}
+		if rgb != [3]float64{want, want, want} {
+			t.Errorf("grid cell %d: expected %v, got %v", idx, want, rgb)
+		}
+	}
+}
@@ -0,0 +1,327 @@
+package imaging
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/ramadoka/penguin-logic/pkg/euclidean"
+	"github.com/ramadoka/penguin-logic/pkg/queue"
+)
+
+// The in-game counters are white glyphs drawn over a dark plate. Text is
+// read by masking the bright pixels that touch the plate, splitting the
+// mask in connected glyphs and matching every glyph against templates.
+
+const (
+	// glyphCols x glyphRows is the grid every glyph is resampled to.
+	glyphCols = 6
+	glyphRows = 9
+	// inkReach is how far, in pixels, a bright pixel looks for the dark
+	// plate before being dropped from the mask.
+	inkReach = 4
+)
+
+type glyphTemplate struct {
+	char   rune
+	aspect float64
+	cells  [glyphRows][glyphCols]float64
+}
+
+// component is a 4-connected set of ink pixels.
+type component struct {
+	pixels []euclidean.Point
+	left   euclidean.X
+	right  euclidean.X
+	top    euclidean.Y
+	bottom euclidean.Y
+}
+
+func (c component) width() int {
+	return int(c.right-c.left) + 1
+}
+
+func (c component) height() int {
+	return int(c.bottom-c.top) + 1
+}
+
+// textLine is a run of glyphs sharing the same baseline, read left to
+// right. Its confidence is the mean confidence of its glyphs.
+type textLine struct {
+	glyphs     []component
+	text       string
+	bound      euclidean.IBound
+	confidence float64
+}
+
+func isBright(c [3]float64) bool {
+	lo := math.Min(c[0], math.Min(c[1], c[2]))
+	hi := math.Max(c[0], math.Max(c[1], c[2]))
+	return lo >= 160 && hi-lo <= 60
+}
+
+func isPlate(c [3]float64) bool {
+	return math.Max(c[0], math.Max(c[1], c[2])) <= 110
+}
+
+// inkMask flags the bright pixels of region lying within inkReach pixels of
+// a plate pixel, horizontally or vertically. Plate is the predicate telling
+// the background of the text apart.
+func inkMask(img Image, region euclidean.IBound, plate func([3]float64) bool) ([]bool, int, int) {
+	w, h := int(region.Width()), int(region.Height())
+	bright := make([]bool, w*h)
+	dark := make([]bool, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			p := euclidean.P2(region.Left()+euclidean.X(x), region.Top()+euclidean.Y(y))
+			if !inside(img, p) {
+				continue
+			}
+			c := rgb8(img, p)
+			bright[y*w+x] = isBright(c)
+			dark[y*w+x] = plate(c)
+		}
+	}
+	mask := make([]bool, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if !bright[y*w+x] {
+				continue
+			}
+			for d := 1; d <= inkReach && !mask[y*w+x]; d++ {
+				mask[y*w+x] = (x-d >= 0 && dark[y*w+x-d]) ||
+					(x+d < w && dark[y*w+x+d]) ||
+					(y-d >= 0 && dark[(y-d)*w+x]) ||
+					(y+d < h && dark[(y+d)*w+x])
+			}
+		}
+	}
+	return mask, w, h
+}
+
+// components splits the mask in 4-connected components, in region
+// coordinates.
+func components(mask []bool, w, h int, origin euclidean.Point) []component {
+	seen := make([]bool, len(mask))
+	out := []component{}
+	for start := range mask {
+		if !mask[start] || seen[start] {
+			continue
+		}
+		seen[start] = true
+		q := queue.New([]int{start})
+		c := component{left: math.MaxInt, top: math.MaxInt, right: math.MinInt, bottom: math.MinInt}
+		for {
+			ok, idx := q.Dequeue()
+			if !ok {
+				break
+			}
+			x, y := idx%w, idx/w
+			p := origin.Add(euclidean.P2(euclidean.X(x), euclidean.Y(y)))
+			c.pixels = append(c.pixels, p)
+			c.left, c.right = min(c.left, p.X), max(c.right, p.X)
+			c.top, c.bottom = min(c.top, p.Y), max(c.bottom, p.Y)
+			neighbours := [4][2]int{{x - 1, y}, {x + 1, y}, {x, y - 1}, {x, y + 1}}
+			for _, n := range neighbours {
+				if n[0] < 0 || n[0] >= w || n[1] < 0 || n[1] >= h {
+					continue
+				}
+				next := n[1]*w + n[0]
+				if mask[next] && !seen[next] {
+					seen[next] = true
+					q.Enqueue(next)
+				}
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// framed drops the components that do not have the plate on their left
+// side on at least half of their rows, such as the rim of an icon bordering
+// the plate.
+func framed(img Image, comps []component, plate func([3]float64) bool) []component {
+	out := []component{}
+	for _, c := range comps {
+		rows := 0
+		for y := c.top; y <= c.bottom; y++ {
+			for d := 1; d <= inkReach; d++ {
+				p := euclidean.P2(c.left-euclidean.X(d), y)
+				if inside(img, p) && plate(rgb8(img, p)) {
+					rows++
+					break
+				}
+			}
+		}
+		if 2*rows >= c.height() {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// readLines groups the glyph sized components on a common baseline and
+// reads every run of close glyphs. A glyph is between minHeight and
+// maxHeight pixels tall; smaller components sitting on the baseline
+// between two glyphs are read as decimal points.
+func readLines(comps []component, minHeight, maxHeight int) []textLine {
+	comps = mergeFragments(comps)
+	glyphs := []component{}
+	dots := []component{}
+	for _, c := range comps {
+		switch {
+		case c.height() >= minHeight && c.height() <= maxHeight && c.width() <= maxHeight:
+			glyphs = append(glyphs, c)
+		case c.height() < minHeight/2 && c.width() < minHeight/2:
+			dots = append(dots, c)
+		}
+	}
+	sort.Slice(glyphs, func(i, j int) bool {
+		return glyphs[i].left < glyphs[j].left
+	})
+
+	lines := []textLine{}
+	used := make([]bool, len(glyphs))
+	for i := range glyphs {
+		if used[i] {
+			continue
+		}
+		run := []component{glyphs[i]}
+		used[i] = true
+		for j := i + 1; j < len(glyphs); j++ {
+			last := run[len(run)-1]
+			g := glyphs[j]
+			if used[j] || absInt(int(g.bottom-last.bottom)) > 2 {
+				continue
+			}
+			if int(g.left-last.right) > last.height()*3/4 {
+				break
+			}
+			run = append(run, g)
+			used[j] = true
+		}
+		lines = append(lines, readRun(run, dots))
+	}
+	return lines
+}
+
+// mergeFragments joins the components stacked on top of another one, a
+// thin stroke of a glyph often breaking it in two.
+func mergeFragments(comps []component) []component {
+	out := make([]component, len(comps))
+	copy(out, comps)
+	for merged := true; merged; {
+		merged = false
+		for i := 0; i < len(out) && !merged; i++ {
+			for j := i + 1; j < len(out); j++ {
+				a, b := out[i], out[j]
+				nested := b.left >= a.left && b.right <= a.right || a.left >= b.left && a.right <= b.right
+				gap := max(int(b.top-a.bottom), int(a.top-b.bottom))
+				if !nested || gap > 1 {
+					continue
+				}
+				out[i] = component{
+					pixels: append(append([]euclidean.Point{}, a.pixels...), b.pixels...),
+					left:   min(a.left, b.left),
+					right:  max(a.right, b.right),
+					top:    min(a.top, b.top),
+					bottom: max(a.bottom, b.bottom),
+				}
+				out = append(out[:j], out[j+1:]...)
+				merged = true
+				break
+			}
+		}
+	}
+	return out
+}
+
+func readRun(run []component, dots []component) textLine {
+	var text strings.Builder
+	confidence := 0.0
+	left, top := run[0].left, run[0].top
+	right, bottom := run[0].right, run[0].bottom
+	for idx, g := range run {
+		if idx > 0 {
+			prev := run[idx-1]
+			for _, d := range dots {
+				if d.left > prev.right && d.right < g.left && absInt(int(d.bottom-g.bottom)) <= 2 {
+					text.WriteRune('.')
+					break
+				}
+			}
+		}
+		char, c := classifyGlyph(g)
+		text.WriteRune(char)
+		confidence += c / float64(len(run))
+		left, right = min(left, g.left), max(right, g.right)
+		top, bottom = min(top, g.top), max(bottom, g.bottom)
+	}
+	return textLine{
+		glyphs:     run,
+		text:       text.String(),
+		bound:      euclidean.Bound(euclidean.P2(left, top), euclidean.P2(right+1, bottom+1)),
+		confidence: confidence,
+	}
+}
+
+// glyphCells resamples a component on the glyph grid, every cell holding
+// the share of its pixels that are ink.
+func glyphCells(c component) [glyphRows][glyphCols]float64 {
+	w, h := c.width(), c.height()
+	ink := make([]bool, w*h)
+	for _, p := range c.pixels {
+		ink[int(p.Y-c.top)*w+int(p.X-c.left)] = true
+	}
+	cells := [glyphRows][glyphCols]float64{}
+	for row := 0; row < glyphRows; row++ {
+		y0, y1 := row*h/glyphRows, max((row+1)*h/glyphRows, row*h/glyphRows+1)
+		for col := 0; col < glyphCols; col++ {
+			x0, x1 := col*w/glyphCols, max((col+1)*w/glyphCols, col*w/glyphCols+1)
+			total, on := 0, 0
+			for y := y0; y < min(y1, h); y++ {
+				for x := x0; x < min(x1, w); x++ {
+					total++
+					if ink[y*w+x] {
+						on++
+					}
+				}
+			}
+			if total > 0 {
+				cells[row][col] = float64(on) / float64(total)
+			}
+		}
+	}
+	return cells
+}
+
+// classifyGlyph returns the closest template and a confidence in [0, 1]
+// shrinking with the distance to it.
+func classifyGlyph(c component) (rune, float64) {
+	cells := glyphCells(c)
+	aspect := float64(c.width()) / float64(c.height())
+	best, bestDist := '?', math.Inf(1)
+	for _, t := range glyphTemplates {
+		d := 0.0
+		for row := range cells {
+			for col := range cells[row] {
+				diff := cells[row][col] - t.cells[row][col]
+				d += diff * diff
+			}
+		}
+		ratio := math.Log(aspect / t.aspect)
+		d += 4 * ratio * ratio
+		if d < bestDist {
+			best, bestDist = t.char, d
+		}
+	}
+	return best, math.Max(0, 1-bestDist/(glyphRows*glyphCols*0.25))
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,137 @@
+package imaging
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ramadoka/penguin-logic/pkg/euclidean"
+)
+
+// Level is one image of a pyramid with its integral. Scale is its size
+// relative to the base image, 1 for the base itself.
+type Level struct {
+	Image    Image
+	Integral IntegralImage
+	Scale    float64
+}
+
+type IPyramid interface {
+	Levels() []Level
+	Level(n int) Level
+	// ToLevel maps a bound of the base image to level n, and FromLevel maps
+	// a bound of level n back to the base image, both through Transform with
+	// the corners rounded to the closest pixel.
+	ToLevel(b euclidean.IBound, n int) euclidean.IBound
+	FromLevel(b euclidean.IBound, n int) euclidean.IBound
+	// Transform maps the sub-pixel coordinates of the base image to level n
+	// along the actual size ratios of the images, which rounding makes
+	// slightly off Scale; its inverse maps them back.
+	Transform(n int) euclidean.Affine
+	Detect(r IRecognition, levels ...int) ([]Detection, error)
+}
+
+type pyramid struct {
+	levels []Level
+}
+
+// Pyramid downscales img by factor, with the area filter, until its
+// smaller side would fall under minSide pixels. Every level is resized from
+// img itself, so that rounding does not add up from one level to the next.
+func Pyramid(img Image, factor float64, minSide int) (IPyramid, error) {
+	if factor <= 0 || factor >= 1 {
+		return nil, fmt.Errorf("invalid pyramid factor %v, expected it in (0, 1)", factor)
+	}
+	if min(int(img.Width()), int(img.Height())) < minSide {
+		return nil, fmt.Errorf("image of %d x %d smaller than %d pixels", img.Width(), img.Height(), minSide)
+	}
+	p := &pyramid{}
+	for scale := 1.0; ; scale *= factor {
+		side := math.Round(float64(min(int(img.Width()), int(img.Height()))) * scale)
+		if side < float64(max(minSide, 1)) {
+			break
+		}
+		level := img
+		if scale < 1 {
+			resized, err := img.ScaleBy(scale, FilterArea)
+			if err != nil {
+				return nil, err
+			}
+			level = resized
+		}
+		integral, err := level.Integral()
+		if err != nil {
+			return nil, err
+		}
+		p.levels = append(p.levels, Level{Image: level, Integral: integral, Scale: scale})
+	}
+	return p, nil
+}
+
+// Levels lists the levels from the base image, level 0, to the coarsest.
+func (p *pyramid) Levels() []Level {
+	return p.levels
+}
+
+func (p *pyramid) Level(n int) Level {
+	return p.levels[n]
+}
+
+func (p *pyramid) ToLevel(b euclidean.IBound, n int) euclidean.IBound {
+	return roundBound(p.Transform(n), b)
+}
+
+func (p *pyramid) FromLevel(b euclidean.IBound, n int) euclidean.IBound {
+	// a scaling between two non-empty images is always invertible
+	inverse, _ := p.Transform(n).Invert()
+	return roundBound(inverse, b)
+}
+
+func (p *pyramid) Transform(n int) euclidean.Affine {
+	base, level := p.levels[0].Image, p.levels[n].Image
+	return euclidean.Scaling(
+		float64(level.Width())/float64(base.Width()),
+		float64(level.Height())/float64(base.Height()),
+	)
+}
+
+// Detect runs r on the given levels, every level when none is given, and
+// maps the windows found back to the base image, where r refines them. A
+// window of a fixed size thus finds objects 1/Scale times larger on every
+// level, which covers an unknown UI scale without scanning larger windows.
+func (p *pyramid) Detect(r IRecognition, levels ...int) ([]Detection, error) {
+	if len(levels) == 0 {
+		for n := range p.levels {
+			levels = append(levels, n)
+		}
+	}
+	base := p.levels[0].Integral
+	detections := []Detection{}
+	for _, n := range levels {
+		if n < 0 || n >= len(p.levels) {
+			return nil, fmt.Errorf("no level %d in a pyramid of %d", n, len(p.levels))
+		}
+		found, err := r.DetectIn(p.levels[n].Integral)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range found {
+			if n > 0 {
+				if d, err = r.Refine(base, p.FromLevel(d.Bound, n)); err != nil {
+					return nil, err
+				}
+				if d.Score <= 0 {
+					continue
+				}
+			}
+			d.Level = n
+			detections = append(detections, d)
+		}
+	}
+	return detections, nil
+}
+
+// roundBound maps both corners of b through the scaling t, rounding them to
+// the closest pixel.
+func roundBound(t euclidean.Affine, b euclidean.IBound) euclidean.IBound {
+	return euclidean.Bound(t.Apply(b.TopLeft().F()).Round(), t.Apply(b.BottomRight().F()).Round())
+}
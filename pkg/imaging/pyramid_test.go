@@ -0,0 +1,146 @@
+package imaging_test
+
+import (
+	"image"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/ramadoka/penguin-logic/pkg/euclidean"
+	"github.com/ramadoka/penguin-logic/pkg/imaging"
+)
+
+func TestPyramidLevels(t *testing.T) {
+	img, err := imaging.Load(inputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pyramid, err := imaging.Pyramid(img, 0.5, 130)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sizes := [][2]int{{1205, 581}, {603, 291}, {301, 145}}
+	if len(pyramid.Levels()) != len(sizes) {
+		t.Fatalf("expected %d levels, got %d", len(sizes), len(pyramid.Levels()))
+	}
+	for n, size := range sizes {
+		level := pyramid.Level(n)
+		if int(level.Image.Width()) != size[0] || int(level.Image.Height()) != size[1] {
+			t.Errorf("level %d: expected %d x %d, got %d x %d", n, size[0], size[1], level.Image.Width(), level.Image.Height())
+		}
+		if level.Integral.Width() != level.Image.Width() || level.Integral.Height() != level.Image.Height() {
+			t.Errorf("level %d: integral of %d x %d", n, level.Integral.Width(), level.Integral.Height())
+		}
+	}
+
+	cell := euclidean.Bound(euclidean.P2(522, 169), euclidean.P2(652, 299))
+	coarse := pyramid.ToLevel(cell, 2)
+	if coarse.Left() != 130 || coarse.Top() != 42 || coarse.Right() != 163 || coarse.Bottom() != 75 {
+		t.Errorf("expected the cell at (130, 42) (163, 75) on level 2, got %s", coarse.ToString())
+	}
+	back := pyramid.FromLevel(coarse, 2)
+	for _, d := range []int{int(back.Left() - cell.Left()), int(back.Top() - cell.Top()), int(back.Right() - cell.Right()), int(back.Bottom() - cell.Bottom())} {
+		if math.Abs(float64(d)) > 2 {
+			t.Errorf("expected %s back from level 2, got %s", cell.ToString(), back.ToString())
+			break
+		}
+	}
+
+	full := euclidean.BoundAt(euclidean.P2(0, 0), img.Width(), img.Height())
+	for n := range sizes {
+		mapped := pyramid.Transform(n).ApplyBound(full)
+		if int(mapped.Width()) != sizes[n][0] || int(mapped.Height()) != sizes[n][1] {
+			t.Errorf("level %d: expected the base image mapped on %d x %d, got %s", n, sizes[n][0], sizes[n][1], mapped.ToString())
+		}
+		inverse, err := pyramid.Transform(n).Invert()
+		if err != nil {
+			t.Fatal(err)
+		}
+		center := inverse.Apply(pyramid.Transform(n).Apply(cell.CenterF()))
+		if math.Abs(center.X-cell.CenterF().X) > 1e-9 || math.Abs(center.Y-cell.CenterF().Y) > 1e-9 {
+			t.Errorf("level %d: expected the center %s back, got %s", n, cell.CenterF().ToString(), center.ToString())
+		}
+	}
+
+	if _, err := imaging.Pyramid(img, 1, 130); err == nil {
+		t.Error("expected an error on a factor of 1")
+	}
+	if _, err := imaging.Pyramid(img, 0.5, 1000); err == nil {
+		t.Error("expected an error on an image smaller than the coarsest level")
+	}
+}
+
+// On an odd-sized image the levels are not exactly Scale times the base,
+// and ToLevel and FromLevel must follow Transform rather than Scale.
+func TestPyramidOddSize(t *testing.T) {
+	img := imaging.New(image.NewRGBA(image.Rect(0, 0, 1001, 577)))
+	pyramid, err := imaging.Pyramid(img, 0.5, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pyramid.Levels()) < 4 {
+		t.Fatalf("expected at least 4 levels, got %d", len(pyramid.Levels()))
+	}
+	r := rand.New(rand.NewSource(20))
+	for n := range pyramid.Levels() {
+		transform := pyramid.Transform(n)
+		inverse, err := transform.Invert()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for range 200 {
+			left, top := euclidean.X(r.Intn(900)), euclidean.Y(r.Intn(500))
+			b := euclidean.BoundAt(euclidean.P2(left, top), euclidean.W(1+r.Intn(100)), euclidean.H(1+r.Intn(77)))
+			want := euclidean.Bound(transform.Apply(b.TopLeft().F()).Round(), transform.Apply(b.BottomRight().F()).Round())
+			if got := pyramid.ToLevel(b, n); got.TopLeft() != want.TopLeft() || got.BottomRight() != want.BottomRight() {
+				t.Fatalf("level %d: expected %s mapped on %s as by Transform, got %s", n, b.ToString(), want.ToString(), got.ToString())
+			}
+			want = euclidean.Bound(inverse.Apply(b.TopLeft().F()).Round(), inverse.Apply(b.BottomRight().F()).Round())
+			if got := pyramid.FromLevel(b, n); got.TopLeft() != want.TopLeft() || got.BottomRight() != want.BottomRight() {
+				t.Fatalf("level %d: expected %s mapped back on %s as by Transform, got %s", n, b.ToString(), want.ToString(), got.ToString())
+			}
+		}
+		full := euclidean.BoundAt(euclidean.P2(0, 0), img.Width(), img.Height())
+		level := pyramid.Level(n).Image
+		if got := pyramid.ToLevel(full, n); got.Width() != level.Width() || got.Height() != level.Height() {
+			t.Errorf("level %d: expected the base image mapped on %d x %d, got %s", n, level.Width(), level.Height(), got.ToString())
+		}
+	}
+}
+
+// A tablet screenshot, twice as large, is found by the 130 pixels window on
+// the second level and refined on the full resolution.
+func TestPyramidDetect(t *testing.T) {
+	original, err := imaging.Load(inputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tablet, err := original.Invert().ScaleBy(2, imaging.FilterNearest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pyramid, err := imaging.Pyramid(tablet, 0.5, 130)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recognizer := imaging.Recognition(imaging.FeatInner5(), imaging.Scanner(130, 130, 32))
+	detections, err := pyramid.Detect(recognizer, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range detections {
+		if d.Level != 1 {
+			t.Fatalf("expected detections on level 1, got %d", d.Level)
+		}
+		if d.Bound.Width() < 255 || d.Bound.Width() > 265 {
+			t.Fatalf("expected windows of about 260 pixels on the base, got %s", d.Bound.ToString())
+		}
+	}
+	lattice, err := imaging.FitLattice(imaging.Suppress(detections, 0.3, imaging.ByScore))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lattice.Rows != 3 || lattice.Cols != 8 {
+		t.Errorf("expected a 3 x 8 depot, got %d x %d", lattice.Rows, lattice.Cols)
+	}
+}
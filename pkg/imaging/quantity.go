@@ -0,0 +1,98 @@
+package imaging
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ramadoka/penguin-logic/pkg/euclidean"
+)
+
+// Quantity is the item count read on the badge of a depot cell.
+type Quantity struct {
+	Text       string
+	Value      int64
+	Bound      euclidean.IBound
+	Confidence float64
+}
+
+// The badge sits in the bottom-right quarter of the item disk; its glyphs
+// are about a ninth of the cell tall.
+const (
+	badgeLeft      = -0.35
+	badgeRight     = 0.45
+	badgeTop       = 0.1
+	badgeBottom    = 0.45
+	badgeMinHeight = 0.07
+	badgeMaxHeight = 0.16
+)
+
+// ReadQuantity looks for the count badge of the cell in bound and reads it.
+// When several runs of glyphs parse as a count, the most confident one
+// wins, the others being stray strokes of the icon read as glyphs.
+func ReadQuantity(img Image, bound euclidean.IBound) (Quantity, error) {
+	size := float64(min(int(bound.Width()), int(bound.Height())))
+	if size <= 0 {
+		return Quantity{}, errors.New("empty cell bound")
+	}
+	center := bound.Center()
+	region := euclidean.Bound(
+		euclidean.P2(center.X+euclidean.X(badgeLeft*size), center.Y+euclidean.Y(badgeTop*size)),
+		euclidean.P2(center.X+euclidean.X(badgeRight*size), center.Y+euclidean.Y(badgeBottom*size)),
+	)
+	mask, w, h := inkMask(img, region, isPlate)
+	comps := framed(img, components(mask, w, h, region.TopLeft()), isPlate)
+	lines := readLines(comps, int(math.Round(badgeMinHeight*size)), int(math.Round(badgeMaxHeight*size)))
+
+	var best *textLine
+	for idx, line := range lines {
+		if _, err := ParseQuantity(line.text); err != nil {
+			continue
+		}
+		if best == nil || line.confidence > best.confidence {
+			best = &lines[idx]
+		}
+	}
+	if best == nil {
+		return Quantity{}, errors.New("no quantity badge found")
+	}
+	value, _ := ParseQuantity(best.text)
+	return Quantity{
+		Text:       best.text,
+		Value:      value,
+		Bound:      best.bound,
+		Confidence: best.confidence,
+	}, nil
+}
+
+// ParseQuantity reads a count as displayed in game: digits with an optional
+// decimal part and an optional K (thousands) or M (millions) suffix, e.g.
+// "5248", "153.8K" or "1.2M".
+func ParseQuantity(text string) (int64, error) {
+	multiplier := 1.0
+	digits := text
+	switch {
+	case strings.HasSuffix(text, "K"):
+		multiplier, digits = 1e3, strings.TrimSuffix(text, "K")
+	case strings.HasSuffix(text, "M"):
+		multiplier, digits = 1e6, strings.TrimSuffix(text, "M")
+	}
+	if digits == "" || strings.HasPrefix(digits, ".") || strings.HasSuffix(digits, ".") {
+		return 0, fmt.Errorf("invalid quantity %q", text)
+	}
+	for _, r := range digits {
+		if (r < '0' || r > '9') && r != '.' {
+			return 0, fmt.Errorf("invalid quantity %q", text)
+		}
+	}
+	if strings.Contains(digits, ".") && multiplier == 1 {
+		return 0, fmt.Errorf("decimal quantity %q without suffix", text)
+	}
+	value, err := strconv.ParseFloat(digits, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid quantity %q: %w", text, err)
+	}
+	return int64(math.Round(value * multiplier)), nil
+}
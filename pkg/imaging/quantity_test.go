@@ -0,0 +1,337 @@
+package imaging_test
+
+import (
+	"image"
+	"image/draw"
+	"testing"
+
+	c "image/color"
+
+	"github.com/ramadoka/penguin-logic/pkg/euclidean"
+	"github.com/ramadoka/penguin-logic/pkg/imaging"
+)
+
+// counterFont is a sans-serif drawn by hand, 16 pixels tall with 3 pixel
+// strokes, apart from the samples the glyph templates were averaged from.
+var counterFont = map[rune][]string{
+	'0': {
+		"..#####..",
+		".#######.",
+		"###...###",
+		"###...###",
+		"###...###",
+		"###...###",
+		"###...###",
+		"###...###",
+		"###...###",
+		"###...###",
+		"###...###",
+		"###...###",
+		"###...###",
+		"###...###",
+		".#######.",
+		"..#####..",
+	},
+	'1': {
+		"..###",
+		".####",
+		"#####",
+		"..###",
+		"..###",
+		"..###",
+		"..###",
+		"..###",
+		"..###",
+		"..###",
+		"..###",
+		"..###",
+		"..###",
+		"..###",
+		"..###",
+		"..###",
+	},
+	'2': {
+		"..#####..",
+		".#######.",
+		"###...###",
+		"......###",
+		"......###",
+		".....###.",
+		"....###..",
+		"...###...",
+		"..###....",
+		".###.....",
+		"###......",
+		"###......",
+		"###......",
+		"###......",
+		"#########",
+		"#########",
+	},
+	'3': {
+		".######..",
+		"#########",
+		"......###",
+		"......###",
+		"......###",
+		".....###.",
+		"..#####..",
+		"..######.",
+		"......###",
+		"......###",
+		"......###",
+		"......###",
+		"###...###",
+		"###...###",
+		".#######.",
+		"..#####..",
+	},
+	'4': {
+		".....###..",
+		"....####..",
+		"...#####..",
+		"..###.###.",
+		"..##..###.",
+		".###..###.",
+		".##...###.",
+		"###...###.",
+		"##########",
+		"##########",
+		"......###.",
+		"......###.",
+		"......###.",
+		"......###.",
+		"......###.",
+		"......###.",
+	},
+	'5': {
+		"#########",
+		"#########",
+		"###......",
+		"###......",
+		"###......",
+		"###.###..",
+		"#########",
+		"####..###",
+		"......###",
+		"......###",
+		"......###",
+		"......###",
+		"###...###",
+		"###...###",
+		".#######.",
+		"..#####..",
+	},
+	'6': {
+		"....#####",
+		"..#######",
+		".###.....",
+		"###......",
+		"###......",
+		"###.###..",
+		"#########",
+		"####..###",
+		"###...###",
+		"###...###",
+		"###...###",
+		"###...###",
+		"###...###",
+		"###...###",
+		".#######.",
+		"..#####..",
+	},
+	'7': {
+		"#########",
+		"#########",
+		"......###",
+		"......###",
+		".....###.",
+		".....###.",
+		"....###..",
+		"....###..",
+		"...###...",
+		"...###...",
+		"...###...",
+		"..###....",
+		"..###....",
+		"..###....",
+		"..###....",
+		"..###....",
+	},
+	'8': {
+		"..#####..",
+		".#######.",
+		"###...###",
+		"###...###",
+		"###...###",
+		".#######.",
+		"..#####..",
+		".#######.",
+		"###...###",
+		"###...###",
+		"###...###",
+		"###...###",
+		"###...###",
+		"###...###",
+		".#######.",
+		"..#####..",
+	},
+	'9': {
+		"..#####..",
+		".#######.",
+		"###...###",
+		"###...###",
+		"###...###",
+		"###...###",
+		"###...###",
+		"###...###",
+		"###..####",
+		"#########",
+		"..###.###",
+		"......###",
+		"......###",
+		"###...###",
+		".#######.",
+		"..#####..",
+	},
+	'K': {
+		"###...###",
+		"###..###.",
+		"###.###..",
+		"######...",
+		"#####....",
+		"####.....",
+		"####.....",
+		"#####....",
+		"######...",
+		"###.###..",
+		"###..###.",
+		"###...###",
+		"###...###",
+		"###...###",
+		"###...###",
+		"###...###",
+	},
+	'M': {
+		"###......###",
+		"####....####",
+		"#####..#####",
+		"############",
+		"###.####.###",
+		"###..##..###",
+		"###......###",
+		"###......###",
+		"###......###",
+		"###......###",
+		"###......###",
+		"###......###",
+		"###......###",
+		"###......###",
+		"###......###",
+		"###......###",
+	},
+	'.': {
+		"###",
+		"###",
+		"###",
+	},
+}
+
+// counter renders text in white on the dark plate of a badge, where
+// ReadQuantity looks for it in a cell of 130 pixels scaled by scale.
+func counter(t *testing.T, text string, scale int) (imaging.Image, euclidean.IBound) {
+	t.Helper()
+	src := image.NewRGBA(image.Rect(0, 0, 130, 130))
+	draw.Draw(src, src.Bounds(), &image.Uniform{c.RGBA{R: 40, G: 36, B: 44, A: 255}}, image.Point{}, draw.Src)
+	x, baseline := 28, 106
+	for _, r := range text {
+		rows, ok := counterFont[r]
+		if !ok {
+			t.Fatalf("no glyph for %q", r)
+		}
+		for dy, row := range rows {
+			for dx, ink := range row {
+				if ink == '#' {
+					src.Set(x+dx, baseline-len(rows)+dy, c.White)
+				}
+			}
+		}
+		x += len(rows[0]) + 2
+	}
+	img := imaging.New(src)
+	if scale > 1 {
+		scaled, err := img.Resize(euclidean.W(130*scale), euclidean.H(130*scale), imaging.FilterNearest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		img = scaled
+	}
+	return img, euclidean.BoundAt(euclidean.P2(0, 0), img.Width(), img.Height())
+}
+
+func TestReadQuantityRendered(t *testing.T) {
+	for _, tc := range []struct {
+		text  string
+		value int64
+	}{
+		{"5248", 5248},
+		{"907", 907},
+		{"153.8K", 153800},
+		{"61K", 61000},
+		{"36M", 36000000},
+		{"1.2M", 1200000},
+	} {
+		for _, scale := range []int{1, 2} {
+			img, bound := counter(t, tc.text, scale)
+			quantity, err := imaging.ReadQuantity(img, bound)
+			if err != nil {
+				t.Errorf("%q at x%d: %v", tc.text, scale, err)
+				continue
+			}
+			if quantity.Text != tc.text || quantity.Value != tc.value {
+				t.Errorf("%q at x%d: expected %d, got %q (%d, %.2f)", tc.text, scale, tc.value, quantity.Text, quantity.Value, quantity.Confidence)
+			}
+		}
+	}
+}
+
+// TestReadQuantity is a smoke test on the screenshot the glyph templates
+// were averaged from, which TestReadQuantityRendered does not share.
+func TestReadQuantity(t *testing.T) {
+	expected := [][]string{
+		{"124", "153.8K", "5248", "5", "1", "184", "316", "48"},
+		{"130", "34", "105", "19", "2", "79", "491", "377"},
+		{"1", "47", "1", "13", "5", "10", "1", "62"},
+	}
+	img, placements := depot(t)
+	for _, p := range placements {
+		quantity, err := imaging.ReadQuantity(img, p.CellBound)
+		if err != nil {
+			t.Errorf("cell %+v: %v", p.Cell, err)
+			continue
+		}
+		if want := expected[p.Cell.Row][p.Cell.Col]; quantity.Text != want {
+			t.Errorf("cell %+v: expected %q, got %q (%.2f)", p.Cell, want, quantity.Text, quantity.Confidence)
+		}
+	}
+}
+
+func TestParseQuantity(t *testing.T) {
+	valid := map[string]int64{
+		"5248":   5248,
+		"153.8K": 153800,
+		"12K":    12000,
+		"1.2M":   1200000,
+	}
+	for text, want := range valid {
+		got, err := imaging.ParseQuantity(text)
+		if err != nil || got != want {
+			t.Errorf("%q: expected %d, got %d (%v)", text, want, got, err)
+		}
+	}
+	for _, text := range []string{"", "K", "1.5", ".5K", "1.K", "1?2", "1..2K"} {
+		if got, err := imaging.ParseQuantity(text); err == nil {
+			t.Errorf("%q: expected an error, got %d", text, got)
+		}
+	}
+}
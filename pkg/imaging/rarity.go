@@ -0,0 +1,195 @@
+package imaging
+
+import (
+	"math"
+
+	"github.com/ramadoka/penguin-logic/pkg/color"
+	"github.com/ramadoka/penguin-logic/pkg/euclidean"
+)
+
+// Rarity is the tier shown by the colored ring around a depot item.
+type Rarity int
+
+const (
+	RarityUnknown Rarity = iota
+	RarityGray
+	RarityGreen
+	RarityBlue
+	RarityPurple
+	RarityGold
+	RarityOrange
+)
+
+func Rarities() []Rarity {
+	return []Rarity{RarityGray, RarityGreen, RarityBlue, RarityPurple, RarityGold, RarityOrange}
+}
+
+func (r Rarity) String() string {
+	switch r {
+	case RarityGray:
+		return "gray"
+	case RarityGreen:
+		return "green"
+	case RarityBlue:
+		return "blue"
+	case RarityPurple:
+		return "purple"
+	case RarityGold:
+		return "gold"
+	case RarityOrange:
+		return "orange"
+	default:
+		return "unknown"
+	}
+}
+
+// ringTone is the hue, saturation and value range a ring pixel of a tier
+// falls in, hue in degrees, saturation and value in [0, 1].
+type ringTone struct {
+	rarity     Rarity
+	hueMin     float64
+	hueMax     float64
+	satMin     float64
+	satMax     float64
+	valMin     float64
+	valMax     float64
+	achromatic bool
+}
+
+// The gold and green rings only differ by ~15 degrees of hue, the purple
+// ring is much darker than the others and the gray one has no hue at all.
+var ringTones = []ringTone{
+	{rarity: RarityOrange, hueMin: 12, hueMax: 38, satMin: 0.6, satMax: 1, valMin: 0.7, valMax: 1},
+	{rarity: RarityGold, hueMin: 38, hueMax: 55, satMin: 0.6, satMax: 1, valMin: 0.7, valMax: 1},
+	{rarity: RarityGreen, hueMin: 55, hueMax: 110, satMin: 0.5, satMax: 1, valMin: 0.5, valMax: 1},
+	{rarity: RarityBlue, hueMin: 180, hueMax: 225, satMin: 0.6, satMax: 1, valMin: 0.5, valMax: 1},
+	{rarity: RarityPurple, hueMin: 250, hueMax: 300, satMin: 0.3, satMax: 0.8, valMin: 0.15, valMax: 0.6},
+	{rarity: RarityGray, satMin: 0, satMax: 0.12, valMin: 0.45, valMax: 0.8, achromatic: true},
+}
+
+const (
+	// ringRays is the number of rays cast from the cell center.
+	ringRays = 72
+	// rays start outside of the ring and stop inside of it, in cell sizes.
+	ringOuter = 0.62
+	ringInner = 0.35
+	// ringThickness is how deep the ring is sampled once its outer edge is
+	// found, in cell sizes.
+	ringThickness = 0.035
+	// ringEdge is the 8-bit color distance between two neighbouring pixels
+	// of a ray above which the ray left the background.
+	ringEdge = 60
+)
+
+// ClassifyRarity casts rays from the center of a cell towards its ring,
+// walking inwards from the background until the first sharp color change,
+// and lets the pixels right behind that edge vote for the tone they match.
+// The confidence is the share of the cast rays voting for the winner.
+func ClassifyRarity(img Image, bound euclidean.IBound) (Rarity, float64) {
+	center := bound.Center()
+	size := float64(min(int(bound.Width()), int(bound.Height())))
+	if size <= 0 {
+		return RarityUnknown, 0
+	}
+	votes := map[Rarity]int{}
+	cast := 0
+	for ray := range ringRays {
+		theta := 2 * math.Pi * float64(ray) / ringRays
+		at := func(radius float64) euclidean.Point {
+			x := float64(center.X) + radius*math.Cos(theta)
+			y := float64(center.Y) + radius*math.Sin(theta)
+			return euclidean.P2(euclidean.X(math.Round(x)), euclidean.Y(math.Round(y)))
+		}
+		edge, ok := ringEdgeRadius(img, at, ringOuter*size, ringInner*size)
+		if !ok {
+			continue
+		}
+		cast++
+		rayVotes := map[Rarity]int{}
+		for depth := 1.0; depth <= math.Max(ringThickness*size, 2); depth++ {
+			p := at(edge - depth)
+			if !inside(img, p) {
+				continue
+			}
+			if rarity := ringToneOf(rgb8(img, p)); rarity != RarityUnknown {
+				rayVotes[rarity]++
+			}
+		}
+		if rarity, n := bestVote(rayVotes); n > 0 {
+			votes[rarity]++
+		}
+	}
+	if cast == 0 {
+		return RarityUnknown, 0
+	}
+	// icons are often framed in white or gray, so a gray ring only wins
+	// when no colored ring shows through on a tenth of the rays
+	grays := votes[RarityGray]
+	delete(votes, RarityGray)
+	rarity, n := bestVote(votes)
+	if n < max(2, cast/10) && grays > n {
+		rarity, n = RarityGray, grays
+	}
+	if n == 0 {
+		return RarityUnknown, 0
+	}
+	return rarity, float64(n) / float64(cast)
+}
+
+func ringEdgeRadius(img Image, at func(radius float64) euclidean.Point, outer, inner float64) (float64, bool) {
+	p := at(outer)
+	if !inside(img, p) {
+		return 0, false
+	}
+	prev := rgb8(img, p)
+	for radius := outer - 1; radius > inner; radius-- {
+		p = at(radius)
+		if !inside(img, p) {
+			return 0, false
+		}
+		current := rgb8(img, p)
+		dr, dg, db := current[0]-prev[0], current[1]-prev[1], current[2]-prev[2]
+		if math.Sqrt(dr*dr+dg*dg+db*db) > ringEdge {
+			return radius, true
+		}
+		prev = current
+	}
+	return 0, false
+}
+
+func ringToneOf(c [3]float64) Rarity {
+	h, s, v := color.HSV(c[0]/255, c[1]/255, c[2]/255)
+	for _, tone := range ringTones {
+		if s < tone.satMin || s > tone.satMax || v < tone.valMin || v > tone.valMax {
+			continue
+		}
+		if tone.achromatic || (h >= tone.hueMin && h < tone.hueMax) {
+			return tone.rarity
+		}
+	}
+	return RarityUnknown
+}
+
+// bestVote returns the most voted rarity, ties going to the higher tier.
+func bestVote(votes map[Rarity]int) (Rarity, int) {
+	best, count := RarityUnknown, 0
+	for _, rarity := range Rarities() {
+		if votes[rarity] >= count && votes[rarity] > 0 {
+			best, count = rarity, votes[rarity]
+		}
+	}
+	return best, count
+}
+
+func inside(img Image, p euclidean.Point) bool {
+	return p.X >= img.Left() && p.X < img.Right() && p.Y >= img.Top() && p.Y < img.Bottom()
+}
+
+// rgb8 reads the color of p on an 8-bit scale.
+func rgb8(img Image, p euclidean.Point) [3]float64 {
+	return [3]float64{
+		float64(color.To8(img.Red(p))),
+		float64(color.To8(img.Green(p))),
+		float64(color.To8(img.Blue(p))),
+	}
+}
@@ -0,0 +1,27 @@
+package imaging_test
+
+import (
+	"testing"
+
+	"github.com/ramadoka/penguin-logic/pkg/imaging"
+)
+
+func TestClassifyRarity(t *testing.T) {
+	green, blue, purple, gold := imaging.RarityGreen, imaging.RarityBlue, imaging.RarityPurple, imaging.RarityGold
+	expected := [][]imaging.Rarity{
+		{gold, purple, blue, gold, gold, purple, blue, blue},
+		{gold, purple, blue, gold, gold, purple, green, green},
+		{gold, gold, purple, gold, gold, purple, purple, gold},
+	}
+	img, placements := depot(t)
+	for _, p := range placements {
+		rarity, confidence := imaging.ClassifyRarity(img, p.CellBound)
+		want := expected[p.Cell.Row][p.Cell.Col]
+		if rarity != want {
+			t.Errorf("cell %+v: expected %s, got %s (%.2f)", p.Cell, want, rarity, confidence)
+		}
+		if confidence <= 0 || confidence > 1 {
+			t.Errorf("cell %+v: confidence out of range: %f", p.Cell, confidence)
+		}
+	}
+}
@@ -1,16 +1,197 @@
 package imaging
 
+import (
+	"github.com/ramadoka/penguin-logic/pkg/color"
+	"github.com/ramadoka/penguin-logic/pkg/euclidean"
+)
+
 type recognition struct {
-	integral IntegralImage
 	pattern  IPattern
+	scanner  IScanner
+	channels []color.Channel
+	recenter int
+}
+
+// Detection is a window accepted by a recognizer.
+type Detection struct {
+	Bound euclidean.IBound
+	// Score is the mean channel response divided by the highest response
+	// Pattern can produce on Bound, clamped to [0, 1].
+	Score float64
+	// Responses holds one ApplyFeat result per channel of color.Channels().
+	Responses []int64
+	Pattern   IPattern
+	// Residual is the distance, in pixels, left between the center of Bound
+	// and the sub-pixel median of its gray mass once recentering stopped.
+	Residual float64
+	// Level is the Pyramid level the window was found on, 0 for the full
+	// resolution.
+	Level int
 }
 
 type IRecognition interface {
+	Detect(img Image) ([]Detection, error)
+	DetectIn(integral IntegralImage) ([]Detection, error)
+	Refine(integral IntegralImage, b euclidean.IBound) (Detection, error)
 }
 
-func Recognition(i *IntegralImage, p IPattern) *recognition {
+// Recognition scans an image with s and reports the windows matching p on
+// at least all but one color channel, the same acceptance rule as
+// IntegralImage.Guess, and scoring above 0 once refined: a flat window
+// responds 0 on every channel and is no item.
+func Recognition(p IPattern, s IScanner) IRecognition {
 	return &recognition{
-		integral: *i,
 		pattern:  p,
+		scanner:  s,
+		channels: color.Channels(),
+		recenter: 5,
+	}
+}
+
+func (r *recognition) Detect(img Image) ([]Detection, error) {
+	integral, err := img.Integral()
+	if err != nil {
+		return nil, err
+	}
+	return r.DetectIn(integral)
+}
+
+// DetectIn is Detect on an integral image already built, such as the
+// levels of a Pyramid.
+func (r *recognition) DetectIn(integral IntegralImage) ([]Detection, error) {
+	detections := []Detection{}
+	var failed error
+	err := r.scanner.Scan(integral, r.channels, r.pattern, func(c Candidate) bool {
+		if !accepted(c.Responses) {
+			return true
+		}
+		detection, err := r.Refine(integral, c.Bound)
+		if err != nil {
+			failed = err
+			return false
+		}
+		if detection.Score <= 0 {
+			return true
+		}
+		detections = append(detections, detection)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if failed != nil {
+		return nil, failed
+	}
+	return detections, nil
+}
+
+// Refine recenters b on its gray center of mass, as long as it stays in
+// the image, and scores the pattern there.
+func (r *recognition) Refine(integral IntegralImage, b euclidean.IBound) (Detection, error) {
+	frame := euclidean.BoundAt(euclidean.P2(0, 0), integral.Width(), integral.Height())
+	recentered, err := integral.BoundRecenter(color.ChannelGray, b, r.recenter)
+	if err != nil {
+		return Detection{}, err
+	}
+	if recentered.In(frame) {
+		b = recentered
+	}
+	responses := make([]int64, len(r.channels))
+	for idx, channel := range r.channels {
+		if responses[idx], err = integral.ApplyFeat(channel, b, r.pattern); err != nil {
+			return Detection{}, err
+		}
+	}
+	center, err := medianF(integral, color.ChannelGray, b)
+	if err != nil {
+		return Detection{}, err
+	}
+	residual := center.Sub(b.CenterF())
+	return Detection{
+		Bound:     b,
+		Score:     score(responses, b, r.pattern),
+		Responses: responses,
+		Pattern:   r.pattern,
+		Residual:  residual.Len(),
+	}, nil
+}
+
+// medianF is the median of the channel mass of b along each axis, to a
+// fraction of a pixel: the mass of the median pixel's column, and row, is
+// taken as spread evenly over it. It is the pixel center of the median
+// pixel when that column or row is empty.
+func medianF(integral IntegralImage, channel color.Channel, b euclidean.IBound) (euclidean.PointF, error) {
+	p, err := integral.CenterOfMass(channel, b)
+	if err != nil {
+		return euclidean.PointF{}, err
+	}
+	total, err := integral.Calculate(channel, b)
+	if err != nil {
+		return euclidean.PointF{}, err
+	}
+	half := float64(total) / 2
+	within := func(before, at euclidean.IBound) (float64, error) {
+		mass, err := integral.Calculate(channel, at)
+		if err != nil || mass == 0 {
+			return 0.5, err
+		}
+		prior, err := integral.Calculate(channel, before)
+		if err != nil {
+			return 0, err
+		}
+		return min(max((half-float64(prior))/float64(mass), 0), 1), nil
+	}
+	dx, err := within(
+		euclidean.Bound(b.TopLeft(), euclidean.P2(p.X, b.Bottom())),
+		euclidean.Bound(euclidean.P2(p.X, b.Top()), euclidean.P2(p.X+1, b.Bottom())),
+	)
+	if err != nil {
+		return euclidean.PointF{}, err
+	}
+	dy, err := within(
+		euclidean.Bound(b.TopLeft(), euclidean.P2(b.Right(), p.Y)),
+		euclidean.Bound(euclidean.P2(b.Left(), p.Y), euclidean.P2(b.Right(), p.Y+1)),
+	)
+	if err != nil {
+		return euclidean.PointF{}, err
+	}
+	return p.F().Add(euclidean.V(dx, dy)), nil
+}
+
+// accepted tells whether at most one response is negative and at least
+// one is positive.
+func accepted(responses []int64) bool {
+	offense, positive := 0, false
+	for _, v := range responses {
+		if v < 0 {
+			offense++
+		}
+		positive = positive || v > 0
+	}
+	return offense <= 1 && positive
+}
+
+func score(responses []int64, b euclidean.IBound, p IPattern) float64 {
+	if len(responses) == 0 {
+		return 0
+	}
+	positive := 0
+	for _, row := range p.Inverse() {
+		for _, m := range row {
+			if m > 0 {
+				positive += m
+			}
+		}
+	}
+	cellArea := float64(b.Width().Mul(b.Height())) / float64(p.Width()*p.Height())
+	best := float64(color.Max) * cellArea * float64(positive)
+	if best <= 0 {
+		return 0
+	}
+	sum := int64(0)
+	for _, v := range responses {
+		sum += v
 	}
+	s := float64(sum) / float64(len(responses)) / best
+	return min(max(s, 0), 1)
 }
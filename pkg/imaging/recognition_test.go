@@ -0,0 +1,142 @@
+package imaging_test
+
+import (
+	"image"
+	"image/draw"
+	"math"
+	"testing"
+
+	"github.com/ramadoka/penguin-logic/pkg/euclidean"
+	"github.com/ramadoka/penguin-logic/pkg/imaging"
+)
+
+const inputPath = "../../integrations/inputs/1.png"
+
+func TestRecognitionDetect(t *testing.T) {
+	original, err := imaging.Load(inputPath)
+	if err != nil {
+		t.Fatalf("failed to load image: %v", err)
+	}
+	recognizer := imaging.Recognition(imaging.FeatInner5(), imaging.Scanner(130, 130, 32))
+	detections, err := recognizer.Detect(original.Invert())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for idx, bound := range tcs() {
+		found := false
+		for _, d := range detections {
+			if bound.Contains(d.Bound.Center()) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("%d. no detection centered in %s", idx, bound)
+		}
+	}
+	for _, d := range detections {
+		if len(d.Responses) != 3 {
+			t.Errorf("expected 3 channel responses, got %d", len(d.Responses))
+		}
+		if d.Score < 0 || d.Score > 1 {
+			t.Errorf("score out of range: %f", d.Score)
+		}
+	}
+}
+
+func TestRecognitionBlank(t *testing.T) {
+	recognizer := imaging.Recognition(imaging.FeatInner5(), imaging.Scanner(130, 130, 32))
+	for _, fill := range []*image.Uniform{image.White, image.Black} {
+		src := image.NewRGBA(image.Rect(0, 0, 400, 300))
+		draw.Draw(src, src.Bounds(), fill, image.Point{}, draw.Src)
+		detections, err := recognizer.Detect(imaging.New(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(detections) != 0 {
+			t.Errorf("expected no detection on a blank image, got %d, the first scoring %f", len(detections), detections[0].Score)
+		}
+	}
+}
+
+// A window centered on a symmetric blob has no residual, whatever the parity
+// of the blob, and one shifted by a pixel has.
+func TestRefineResidual(t *testing.T) {
+	recognizer := imaging.Recognition(imaging.FeatInner5(), imaging.Scanner(40, 40, 8))
+	for _, tc := range []struct {
+		blob   image.Rectangle
+		window euclidean.IBound
+		want   float64
+	}{
+		{image.Rect(20, 20, 30, 30), euclidean.BoundAt(euclidean.P2(5, 5), 40, 40), 0},
+		{image.Rect(20, 20, 31, 31), euclidean.BoundAt(euclidean.P2(5, 5), 41, 41), 0},
+		{image.Rect(20, 20, 30, 30), euclidean.BoundAt(euclidean.P2(6, 6), 40, 40), math.Sqrt2},
+		{image.Rect(20, 20, 30, 30), euclidean.BoundAt(euclidean.P2(4, 5), 40, 40), 1},
+	} {
+		src := image.NewRGBA(image.Rect(0, 0, 50, 50))
+		draw.Draw(src, src.Bounds(), image.Black, image.Point{}, draw.Src)
+		draw.Draw(src, tc.blob, image.White, image.Point{}, draw.Src)
+		integral, err := imaging.New(src).Integral()
+		if err != nil {
+			t.Fatal(err)
+		}
+		d, err := recognizer.Refine(integral, tc.window)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(d.Residual-tc.want) > 1e-9 {
+			t.Errorf("blob %v in %s: expected a residual of %f, got %f", tc.blob, tc.window.ToString(), tc.want, d.Residual)
+		}
+	}
+}
+
+func TestSuppress(t *testing.T) {
+	box := func(x0, y0, x1, y1 euclidean.X) euclidean.IBound {
+		return euclidean.Bound(euclidean.P2(x0, euclidean.Y(y0)), euclidean.P2(x1, euclidean.Y(y1)))
+	}
+	detections := []imaging.Detection{
+		{Bound: box(0, 0, 10, 10), Score: 0.5, Residual: 1},
+		{Bound: box(1, 1, 11, 11), Score: 0.9, Residual: 3},
+		{Bound: box(2, 0, 12, 10), Score: 0.7, Residual: 0},
+		{Bound: box(20, 20, 30, 30), Score: 0.1, Residual: 4},
+	}
+	if iou := detections[0].Bound.IoU(detections[3].Bound); iou != 0 {
+		t.Errorf("expected disjoint bounds to have IoU 0, got %f", iou)
+	}
+	if iou := detections[0].Bound.IoU(detections[0].Bound); iou != 1 {
+		t.Errorf("expected a bound to have IoU 1 with itself, got %f", iou)
+	}
+
+	kept := imaging.Suppress(detections, 0.3, imaging.ByScore)
+	if len(kept) != 2 || kept[0].Score != 0.9 || kept[1].Score != 0.1 {
+		t.Errorf("unexpected detections kept by score: %+v", kept)
+	}
+	kept = imaging.Suppress(detections, 0.3, imaging.ByRecentering)
+	if len(kept) != 2 || kept[0].Score != 0.7 || kept[1].Score != 0.1 {
+		t.Errorf("unexpected detections kept by recentering: %+v", kept)
+	}
+}
+
+// depot runs the whole detection on 1.png: recognition on the inverted
+// screenshot, suppression and lattice placement.
+func depot(t *testing.T) (imaging.Image, []imaging.Placement) {
+	t.Helper()
+	original, err := imaging.Load(inputPath)
+	if err != nil {
+		t.Fatalf("failed to load image: %v", err)
+	}
+	recognizer := imaging.Recognition(imaging.FeatInner5(), imaging.Scanner(130, 130, 32))
+	detections, err := recognizer.Detect(original.Invert())
+	if err != nil {
+		t.Fatal(err)
+	}
+	detections = imaging.Suppress(detections, 0.3, imaging.ByScore)
+	lattice, err := imaging.FitLattice(detections)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lattice.Rows != 3 || lattice.Cols != 8 {
+		t.Fatalf("expected a 3 x 8 depot, got %d x %d", lattice.Rows, lattice.Cols)
+	}
+	return original, lattice.Place(detections)
+}
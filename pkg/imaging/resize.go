@@ -0,0 +1,139 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/ramadoka/penguin-logic/pkg/color"
+	"github.com/ramadoka/penguin-logic/pkg/euclidean"
+)
+
+// Filter is the way Resize computes a pixel from the ones it covers.
+type Filter int
+
+const (
+	// FilterNearest copies the closest source pixel; fast, and exact for
+	// integer upscaling.
+	FilterNearest Filter = iota
+	// FilterBilinear interpolates the four closest source pixels; smooth
+	// when enlarging, aliased when shrinking by more than half.
+	FilterBilinear
+	// FilterArea averages the source pixels covered by each target pixel,
+	// weighted by the covered area; the one to shrink screenshots with.
+	FilterArea
+)
+
+func (f Filter) String() string {
+	switch f {
+	case FilterNearest:
+		return "nearest"
+	case FilterBilinear:
+		return "bilinear"
+	case FilterArea:
+		return "area"
+	default:
+		return "(unknown)"
+	}
+}
+
+// tap is the weight of one source pixel in a target pixel.
+type tap struct {
+	index  int
+	weight float64
+}
+
+// Resize resamples the image to w x h pixels with filter. The result has its
+// top left corner at the origin, like Crop.
+func (i *image_) Resize(w euclidean.W, h euclidean.H, filter Filter) (Image, error) {
+	if w <= 0 || h <= 0 {
+		return nil, fmt.Errorf("invalid size: %d x %d", w, h)
+	}
+	srcW, srcH := i.bounds.Dx(), i.bounds.Dy()
+	if srcW == 0 || srcH == 0 {
+		return nil, fmt.Errorf("cannot resize an empty image")
+	}
+	columns, err := taps(srcW, int(w), filter)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := taps(srcH, int(h), filter)
+	if err != nil {
+		return nil, err
+	}
+	resized := blank(image.Rect(0, 0, int(w), int(h)))
+	// Every plane is resampled along the rows first, in floating point, then
+	// along the columns.
+	horizontal := make([]float64, srcH*int(w))
+	for k := 0; k < planes; k++ {
+		src, dst := i.plane(k), resized.plane(k)
+		for y := 0; y < srcH; y++ {
+			row := i.row(src, i.bounds.Min.Y+y)
+			for x, weights := range columns {
+				v := 0.0
+				for _, t := range weights {
+					v += t.weight * float64(row[t.index])
+				}
+				horizontal[y*int(w)+x] = v
+			}
+		}
+		for y, weights := range rows {
+			for x := 0; x < int(w); x++ {
+				v := 0.0
+				for _, t := range weights {
+					v += t.weight * horizontal[t.index*int(w)+x]
+				}
+				dst[y*int(w)+x] = uint16(math.Round(min(max(v, 0), color.Max)))
+			}
+		}
+	}
+	return resized, nil
+}
+
+// ScaleBy resizes the image by factor on both axes, rounding the size to
+// the closest pixel and keeping at least one.
+func (i *image_) ScaleBy(factor float64, filter Filter) (Image, error) {
+	if factor <= 0 || math.IsInf(factor, 0) || math.IsNaN(factor) {
+		return nil, fmt.Errorf("invalid scale factor: %v", factor)
+	}
+	w := max(math.Round(float64(i.bounds.Dx())*factor), 1)
+	h := max(math.Round(float64(i.bounds.Dy())*factor), 1)
+	return i.Resize(euclidean.W(w), euclidean.H(h), filter)
+}
+
+// taps lists, for every one of the dst pixels of an axis, the source pixels
+// filter reads and their weights, which sum to 1. Pixel centers are aligned:
+// target pixel x is centered on source coordinate (x+0.5)*src/dst.
+func taps(src, dst int, filter Filter) ([][]tap, error) {
+	ratio := float64(src) / float64(dst)
+	weights := make([][]tap, dst)
+	for x := range weights {
+		center := (float64(x)+0.5)*ratio - 0.5
+		switch filter {
+		case FilterNearest:
+			weights[x] = []tap{{clampIndex(int(math.Floor(center+0.5)), src), 1}}
+		case FilterBilinear:
+			left := math.Floor(center)
+			frac := center - left
+			weights[x] = []tap{
+				{clampIndex(int(left), src), 1 - frac},
+				{clampIndex(int(left)+1, src), frac},
+			}
+		case FilterArea:
+			from, to := float64(x)*ratio, float64(x+1)*ratio
+			for s := int(from); s < src && float64(s) < to; s++ {
+				covered := min(to, float64(s+1)) - max(from, float64(s))
+				if covered > 0 {
+					weights[x] = append(weights[x], tap{s, covered / ratio})
+				}
+			}
+		default:
+			return nil, fmt.Errorf("unknown filter %d", filter)
+		}
+	}
+	return weights, nil
+}
+
+func clampIndex(idx, n int) int {
+	return min(max(idx, 0), n-1)
+}
@@ -0,0 +1,165 @@
+package imaging_test
+
+import (
+	"image"
+	"math"
+	"math/rand"
+	"testing"
+
+	c "image/color"
+
+	"github.com/ramadoka/penguin-logic/pkg/color"
+	"github.com/ramadoka/penguin-logic/pkg/euclidean"
+	"github.com/ramadoka/penguin-logic/pkg/imaging"
+)
+
+func checkerboard(w, h, cell int) *image.RGBA {
+	src := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if (x/cell+y/cell)%2 == 0 {
+				src.Set(x, y, c.White)
+			} else {
+				src.Set(x, y, c.Black)
+			}
+		}
+	}
+	return src
+}
+
+// randomOpaque is a w x h image of random opaque colors, drawn from seed so
+// that a failing test keeps failing on the same pixels.
+func randomOpaque(t *testing.T, w, h int, seed int64) *image.RGBA {
+	t.Helper()
+	src := image.NewRGBA(image.Rect(0, 0, w, h))
+	r := rand.New(rand.NewSource(seed))
+	for idx := range src.Pix {
+		src.Pix[idx] = uint8(r.Intn(256))
+		if idx%4 == 3 {
+			src.Pix[idx] = color.Max8
+		}
+	}
+	return src
+}
+
+func TestResizeSameSize(t *testing.T) {
+	src := checkerboard(9, 7, 2)
+	img := imaging.New(src)
+	for _, filter := range []imaging.Filter{imaging.FilterNearest, imaging.FilterBilinear, imaging.FilterArea} {
+		resized, err := img.Resize(9, 7, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for y := euclidean.Y(0); y < 7; y++ {
+			for x := euclidean.X(0); x < 9; x++ {
+				p := euclidean.P2(x, y)
+				if resized.Red(p) != img.Red(p) {
+					t.Fatalf("%s at %s: expected %d, got %d", filter, p.ToString(), img.Red(p), resized.Red(p))
+				}
+			}
+		}
+	}
+}
+
+func TestResizeFilters(t *testing.T) {
+	img := imaging.New(checkerboard(8, 8, 1))
+
+	nearest, err := img.ScaleBy(3, imaging.FilterNearest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nearest.Width() != 24 || nearest.Height() != 24 {
+		t.Fatalf("expected 24 x 24, got %d x %d", nearest.Width(), nearest.Height())
+	}
+	for y := euclidean.Y(0); y < 24; y++ {
+		for x := euclidean.X(0); x < 24; x++ {
+			p := euclidean.P2(x, y)
+			if want := img.Red(euclidean.P2(x/3, y/3)); nearest.Red(p) != want {
+				t.Fatalf("nearest at %s: expected %d, got %d", p.ToString(), want, nearest.Red(p))
+			}
+		}
+	}
+
+	// every 2 x 2 block of a one pixel checkerboard averages to mid gray
+	area, err := img.Resize(4, 4, imaging.FilterArea)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for y := euclidean.Y(0); y < 4; y++ {
+		for x := euclidean.X(0); x < 4; x++ {
+			if got := area.Green(euclidean.P2(x, y)); math.Abs(float64(got)-color.Max/2) > 1 {
+				t.Fatalf("area at (%d, %d): expected mid gray, got %d", x, y, got)
+			}
+		}
+	}
+
+	// a horizontal ramp stays a ramp, bounded by its ends
+	ramp := image.NewRGBA(image.Rect(0, 0, 4, 1))
+	for x := 0; x < 4; x++ {
+		ramp.Set(x, 0, c.RGBA{B: uint8(x * 85), A: 255})
+	}
+	bilinear, err := imaging.New(ramp).Resize(10, 3, imaging.FilterBilinear)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for x := euclidean.X(1); x < 10; x++ {
+		prev, current := bilinear.Blue(euclidean.P2(x-1, 1)), bilinear.Blue(euclidean.P2(x, 1))
+		if current < prev {
+			t.Fatalf("bilinear at %d: %d is below %d", x, current, prev)
+		}
+	}
+	if first, last := bilinear.Blue(euclidean.P2(0, 0)), bilinear.Blue(euclidean.P2(9, 2)); first != 0 || last != color.Max {
+		t.Errorf("expected the ramp to span 0 to %d, got %d to %d", color.Max, first, last)
+	}
+}
+
+func TestResizeErrors(t *testing.T) {
+	img := imaging.New(checkerboard(4, 4, 1))
+	if _, err := img.Resize(0, 4, imaging.FilterArea); err == nil {
+		t.Error("expected an error on an empty size")
+	}
+	if _, err := img.Resize(4, 4, imaging.Filter(42)); err == nil {
+		t.Error("expected an error on an unknown filter")
+	}
+	if _, err := img.ScaleBy(-1, imaging.FilterArea); err == nil {
+		t.Error("expected an error on a negative factor")
+	}
+}
+
+// A phone screenshot, where cells are about 90 pixels wide, is brought back
+// to the scale the patterns are tuned for: every cell keeps its mean color,
+// at the same place.
+func TestResizeNormalizesScale(t *testing.T) {
+	original, placements := depot(t)
+	phone, err := original.ScaleBy(0.7, imaging.FilterArea)
+	if err != nil {
+		t.Fatal(err)
+	}
+	normalized, err := phone.Resize(original.Width(), original.Height(), imaging.FilterBilinear)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := original.Integral()
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := normalized.Integral()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range placements {
+		for _, channel := range color.Channels() {
+			want, err := before.Mean(channel, p.Bound)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := after.Mean(channel, p.Bound)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(got-want) > 0.01*color.Max {
+				t.Errorf("cell %+v %s: expected a mean of %.0f, got %.0f", p.Cell, channel, want, got)
+			}
+		}
+	}
+}
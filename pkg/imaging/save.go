@@ -0,0 +1,119 @@
+package imaging
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Format is the file format an image is written in.
+type Format int
+
+const (
+	// FormatAuto picks the format from the extension of the path.
+	FormatAuto Format = iota
+	FormatPNG
+	FormatJPEG
+	FormatGIF
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatPNG:
+		return "png"
+	case FormatJPEG:
+		return "jpeg"
+	case FormatGIF:
+		return "gif"
+	default:
+		return "auto"
+	}
+}
+
+// ErrUnsupportedFormat is returned, wrapped, for the extensions and formats
+// Save cannot write.
+var ErrUnsupportedFormat = errors.New("unsupported image format")
+
+// SaveOptions tunes the encoders; the zero value writes the format of the
+// extension with the default settings.
+type SaveOptions struct {
+	Format Format
+	// Quality is the JPEG quality, from 1 to 100, jpeg.DefaultQuality when 0.
+	Quality int
+}
+
+// FormatOf returns the format matching the extension of path.
+func FormatOf(path string) (Format, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".png":
+		return FormatPNG, nil
+	case ".jpg", ".jpeg":
+		return FormatJPEG, nil
+	case ".gif":
+		return FormatGIF, nil
+	default:
+		return FormatAuto, fmt.Errorf("%w: extension %q", ErrUnsupportedFormat, ext)
+	}
+}
+
+// SaveImage writes any image, such as the result of Image.Extract, to path.
+// The file is not created when the format is unsupported.
+func SaveImage(img image.Image, path string, opts SaveOptions) error {
+	if opts.Format == FormatAuto {
+		format, err := FormatOf(path)
+		if err != nil {
+			return err
+		}
+		opts.Format = format
+	}
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := EncodeImage(f, img, opts); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// EncodeImage writes img to w in opts.Format, which cannot be FormatAuto
+// since there is no extension to pick it from.
+func EncodeImage(w io.Writer, img image.Image, opts SaveOptions) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	switch opts.Format {
+	case FormatPNG:
+		return png.Encode(w, img)
+	case FormatJPEG:
+		quality := opts.Quality
+		if quality == 0 {
+			quality = jpeg.DefaultQuality
+		}
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	case FormatGIF:
+		return gif.Encode(w, img, nil)
+	}
+	return fmt.Errorf("%w: %s", ErrUnsupportedFormat, opts.Format)
+}
+
+func (o SaveOptions) validate() error {
+	if o.Format < FormatPNG || o.Format > FormatGIF {
+		return fmt.Errorf("%w: %s", ErrUnsupportedFormat, o.Format)
+	}
+	if o.Quality < 0 || o.Quality > 100 {
+		return fmt.Errorf("invalid JPEG quality %d, expected 1 to 100", o.Quality)
+	}
+	return nil
+}
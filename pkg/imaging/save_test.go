@@ -0,0 +1,95 @@
+package imaging_test
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ramadoka/penguin-logic/pkg/euclidean"
+	"github.com/ramadoka/penguin-logic/pkg/imaging"
+)
+
+func TestSaveByExtension(t *testing.T) {
+	img, err := imaging.Load(inputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cell := img.Crop(euclidean.Bound(euclidean.P2(54, 37), euclidean.P2(184, 167)))
+	dir := t.TempDir()
+	for name, format := range map[string]string{
+		"cell.png":  "png",
+		"cell.JPG":  "jpeg",
+		"cell.jpeg": "jpeg",
+		"cell.gif":  "gif",
+	} {
+		path := filepath.Join(dir, name)
+		if err := cell.Save(path); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config, got, err := image.DecodeConfig(f)
+		f.Close()
+		if err != nil || got != format {
+			t.Errorf("%s: expected %s, got %s (%v)", name, format, got, err)
+		}
+		if config.Width != 130 || config.Height != 130 {
+			t.Errorf("%s: expected 130 x 130, got %d x %d", name, config.Width, config.Height)
+		}
+	}
+
+	reloaded, err := imaging.Load(filepath.Join(dir, "cell.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []euclidean.Point{euclidean.P2(1, 1), euclidean.P2(64, 64), euclidean.P2(129, 129)} {
+		if reloaded.Red(p) != cell.Red(p) || reloaded.Green(p) != cell.Green(p) || reloaded.Blue(p) != cell.Blue(p) {
+			t.Errorf("png at %s is not lossless", p.ToString())
+		}
+	}
+}
+
+func TestSaveOptions(t *testing.T) {
+	img, err := imaging.Load(inputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var low, high bytes.Buffer
+	if err := img.Encode(&low, imaging.SaveOptions{Format: imaging.FormatJPEG, Quality: 20}); err != nil {
+		t.Fatal(err)
+	}
+	if err := img.Encode(&high, imaging.SaveOptions{Format: imaging.FormatJPEG, Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	if low.Len() >= high.Len() {
+		t.Errorf("expected quality 20 to be smaller than 95, got %d and %d bytes", low.Len(), high.Len())
+	}
+
+	dir := t.TempDir()
+	explicit := filepath.Join(dir, "cell.dump")
+	if err := img.SaveAs(explicit, imaging.SaveOptions{Format: imaging.FormatPNG}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := imaging.Load(explicit); err != nil {
+		t.Errorf("explicit format: %v", err)
+	}
+
+	unsupported := filepath.Join(dir, "cell.bmp")
+	if err := img.Save(unsupported); !errors.Is(err, imaging.ErrUnsupportedFormat) {
+		t.Errorf("expected ErrUnsupportedFormat, got %v", err)
+	}
+	if _, err := os.Stat(unsupported); !os.IsNotExist(err) {
+		t.Errorf("expected %s not to be created", unsupported)
+	}
+	if err := img.Encode(&bytes.Buffer{}, imaging.SaveOptions{}); !errors.Is(err, imaging.ErrUnsupportedFormat) {
+		t.Errorf("expected ErrUnsupportedFormat without a format, got %v", err)
+	}
+	if err := img.Encode(&bytes.Buffer{}, imaging.SaveOptions{Format: imaging.FormatJPEG, Quality: 101}); err == nil {
+		t.Error("expected an error on an out of range quality")
+	}
+}
@@ -0,0 +1,96 @@
+package imaging
+
+import (
+	"math"
+
+	"github.com/ramadoka/penguin-logic/pkg/color"
+	"github.com/ramadoka/penguin-logic/pkg/euclidean"
+)
+
+type scanner struct {
+	width  euclidean.W
+	height euclidean.H
+	stride int
+	scales []float64
+}
+
+// Candidate is a window visited by a scanner, with the pattern response
+// on every scanned channel.
+type Candidate struct {
+	Bound     euclidean.IBound
+	Scale     float64
+	Responses []int64
+	Score     float64
+}
+
+type IScanner interface {
+	Windows(w euclidean.W, h euclidean.H) []euclidean.IBound
+	Scan(integral IntegralImage, channels []color.Channel, p IPattern, yield func(Candidate) bool) error
+}
+
+// Scanner walks windows of width x height over a whole image, stride pixels
+// apart. Every scale multiplies both the window and the stride; without
+// scales the window is only walked at its own size.
+func Scanner(width euclidean.W, height euclidean.H, stride int, scales ...float64) IScanner {
+	if len(scales) == 0 {
+		scales = []float64{1}
+	}
+	return &scanner{
+		width:  width,
+		height: height,
+		stride: max(stride, 1),
+		scales: scales,
+	}
+}
+
+// Windows lists, scale by scale and row by row, every window that fits in
+// an image of w x h pixels.
+func (s *scanner) Windows(w euclidean.W, h euclidean.H) []euclidean.IBound {
+	windows := []euclidean.IBound{}
+	s.walk(w, h, func(b euclidean.IBound, _ float64) bool {
+		windows = append(windows, b)
+		return true
+	})
+	return windows
+}
+
+// Scan evaluates p on every window through ApplyFeat and hands the
+// candidates to yield, in the order of Windows, until yield returns false.
+// It stops on the first channel the integral cannot sum.
+func (s *scanner) Scan(integral IntegralImage, channels []color.Channel, p IPattern, yield func(Candidate) bool) error {
+	var err error
+	s.walk(integral.Width(), integral.Height(), func(b euclidean.IBound, scale float64) bool {
+		responses := make([]int64, len(channels))
+		for idx, channel := range channels {
+			if responses[idx], err = integral.ApplyFeat(channel, b, p); err != nil {
+				return false
+			}
+		}
+		return yield(Candidate{
+			Bound:     b,
+			Scale:     scale,
+			Responses: responses,
+			Score:     score(responses, b, p),
+		})
+	})
+	return err
+}
+
+func (s *scanner) walk(w euclidean.W, h euclidean.H, fn func(b euclidean.IBound, scale float64) bool) {
+	for _, scale := range s.scales {
+		width := euclidean.W(math.Round(float64(s.width) * scale))
+		height := euclidean.H(math.Round(float64(s.height) * scale))
+		stride := max(int(math.Round(float64(s.stride)*scale)), 1)
+		if width <= 0 || height <= 0 {
+			continue
+		}
+		for y := euclidean.Y(0); y.Add(height) <= euclidean.Y(h); y += euclidean.Y(stride) {
+			for x := euclidean.X(0); x.Add(width) <= euclidean.X(w); x += euclidean.X(stride) {
+				b := euclidean.BoundAt(euclidean.P2(x, y), width, height)
+				if !fn(b, scale) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package imaging_test
+
+import (
+	"testing"
+
+	"github.com/ramadoka/penguin-logic/pkg/color"
+	"github.com/ramadoka/penguin-logic/pkg/euclidean"
+	"github.com/ramadoka/penguin-logic/pkg/imaging"
+)
+
+func TestScannerWindows(t *testing.T) {
+	s := imaging.Scanner(10, 10, 5, 1, 2)
+	windows := s.Windows(31, 21)
+	// scale 1: x in {0,5,10,15,20}, y in {0,5,10}; scale 2: x in {0,10}, y in {0}
+	if len(windows) != 17 {
+		t.Fatalf("expected 17 windows, got %d", len(windows))
+	}
+	first := windows[0]
+	if !first.TopLeft().Eq(euclidean.P2(0, 0)) || !first.BottomRight().Eq(euclidean.P2(10, 10)) {
+		t.Errorf("unexpected first window %s", first)
+	}
+	last := windows[len(windows)-1]
+	if !last.TopLeft().Eq(euclidean.P2(10, 0)) || last.Width() != 20 {
+		t.Errorf("unexpected last window %s", last)
+	}
+}
+
+func TestScannerScanIsReproducible(t *testing.T) {
+	img, err := imaging.Load(inputPath)
+	if err != nil {
+		t.Fatalf("failed to load image: %v", err)
+	}
+	integral, err := img.Integral()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := imaging.Scanner(130, 130, 40, 0.9, 1)
+	collect := func() []imaging.Candidate {
+		acc := []imaging.Candidate{}
+		err := s.Scan(integral, color.Channels(), imaging.FeatInner5(), func(c imaging.Candidate) bool {
+			acc = append(acc, c)
+			return true
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return acc
+	}
+	first, second := collect(), collect()
+	if len(first) != len(s.Windows(img.Width(), img.Height())) {
+		t.Fatalf("expected one candidate per window, got %d", len(first))
+	}
+	for idx := range first {
+		if first[idx].Bound.ToString() != second[idx].Bound.ToString() || first[idx].Score != second[idx].Score {
+			t.Fatalf("%d. scans differ: %v vs %v", idx, first[idx], second[idx])
+		}
+	}
+
+	visited := 0
+	err = s.Scan(integral, color.Channels(), imaging.FeatInner5(), func(c imaging.Candidate) bool {
+		visited++
+		return visited < 3
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if visited != 3 {
+		t.Errorf("expected scan to stop after 3 candidates, visited %d", visited)
+	}
+}
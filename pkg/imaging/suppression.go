@@ -0,0 +1,48 @@
+package imaging
+
+import (
+	"sort"
+)
+
+// DetectionOrder reports whether a should be kept over b when both cover
+// the same item.
+type DetectionOrder func(a, b Detection) bool
+
+// ByScore prefers the detection with the highest score.
+func ByScore(a, b Detection) bool {
+	return a.Score > b.Score
+}
+
+// ByRecentering prefers the detection whose bound sits closest to its own
+// center of mass, falling back to the score on ties.
+func ByRecentering(a, b Detection) bool {
+	if a.Residual != b.Residual {
+		return a.Residual < b.Residual
+	}
+	return ByScore(a, b)
+}
+
+// Suppress is a greedy non-maximum suppression: detections are visited from
+// best to worst according to better, and each one is dropped when its IoU
+// with an already kept detection exceeds threshold.
+func Suppress(detections []Detection, threshold float64, better DetectionOrder) []Detection {
+	ranked := make([]Detection, len(detections))
+	copy(ranked, detections)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return better(ranked[i], ranked[j])
+	})
+	kept := []Detection{}
+	for _, candidate := range ranked {
+		overlapping := false
+		for _, k := range kept {
+			if candidate.Bound.IoU(k.Bound) > threshold {
+				overlapping = true
+				break
+			}
+		}
+		if !overlapping {
+			kept = append(kept, candidate)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,82 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/ramadoka/penguin-logic/pkg/color"
+	"github.com/ramadoka/penguin-logic/pkg/euclidean"
+)
+
+// Warp maps the image through t, an affine transform turned Homography or
+// an estimated perspective one, and returns the w x h pixels of the result
+// from the origin. Every target pixel samples the image bilinearly where
+// its center comes from; the ones coming from outside of the image are
+// transparent black.
+func (i *image_) Warp(t euclidean.Homography, w euclidean.W, h euclidean.H) (Image, error) {
+	if w <= 0 || h <= 0 {
+		return nil, fmt.Errorf("invalid size: %d x %d", w, h)
+	}
+	inverse, err := t.Invert()
+	if err != nil {
+		return nil, err
+	}
+	srcW, srcH := i.bounds.Dx(), i.bounds.Dy()
+	warped := blank(image.Rect(0, 0, int(w), int(h)))
+	var src, dst [planes][]uint16
+	for k := range planes {
+		src[k], dst[k] = i.plane(k), warped.plane(k)
+	}
+	for y := 0; y < int(h); y++ {
+		for x := 0; x < int(w); x++ {
+			from := inverse.Apply(euclidean.PF(float64(x)+0.5, float64(y)+0.5))
+			// the pixel centers around from, and the share of each
+			sx, sy := from.X-0.5, from.Y-0.5
+			if math.IsNaN(sx) || math.IsNaN(sy) || sx <= -1 || sy <= -1 || sx >= float64(srcW) || sy >= float64(srcH) {
+				continue
+			}
+			left, top := math.Floor(sx), math.Floor(sy)
+			fx, fy := sx-left, sy-top
+			var v [planes]float64
+			for _, tap := range [4]struct {
+				dx, dy int
+				weight float64
+			}{
+				{0, 0, (1 - fx) * (1 - fy)},
+				{1, 0, fx * (1 - fy)},
+				{0, 1, (1 - fx) * fy},
+				{1, 1, fx * fy},
+			} {
+				px, py := int(left)+tap.dx, int(top)+tap.dy
+				if tap.weight == 0 || px < 0 || py < 0 || px >= srcW || py >= srcH {
+					continue
+				}
+				idx := i.index(i.bounds.Min.X+px, i.bounds.Min.Y+py)
+				for k := range planes {
+					v[k] += tap.weight * float64(src[k][idx])
+				}
+			}
+			for k := range planes {
+				dst[k][y*int(w)+x] = uint16(math.Round(min(max(v[k], 0), color.Max)))
+			}
+		}
+	}
+	return warped, nil
+}
+
+// Rectify straightens the quadrilateral of img whose corners are, in this
+// order, the top left, top right, bottom right and bottom left ones into a
+// w x h image, undoing the perspective of a photographed screen.
+func Rectify(img Image, corners [4]euclidean.Point, w euclidean.W, h euclidean.H) (Image, error) {
+	t, err := euclidean.EstimateHomography(corners[:], []euclidean.Point{
+		euclidean.P2(0, 0),
+		euclidean.P2(euclidean.X(w), 0),
+		euclidean.P2(euclidean.X(w), euclidean.Y(h)),
+		euclidean.P2(0, euclidean.Y(h)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return img.Warp(t, w, h)
+}
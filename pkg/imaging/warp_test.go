@@ -0,0 +1,102 @@
+package imaging_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/ramadoka/penguin-logic/pkg/color"
+	"github.com/ramadoka/penguin-logic/pkg/euclidean"
+	"github.com/ramadoka/penguin-logic/pkg/imaging"
+)
+
+func TestWarpAffine(t *testing.T) {
+	img := imaging.New(checkerboard(8, 6, 3))
+	same, err := img.Warp(euclidean.Identity().Homography(), 8, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shifted, err := img.Warp(euclidean.Translation(euclidean.V(2, 1)).Homography(), 8, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for y := euclidean.Y(0); y < 6; y++ {
+		for x := euclidean.X(0); x < 8; x++ {
+			p := euclidean.P2(x, y)
+			if same.Red(p) != img.Red(p) {
+				t.Errorf("at %s: expected the identity to keep %#x, got %#x", p.ToString(), img.Red(p), same.Red(p))
+			}
+			want := color.Red(0)
+			if x >= 2 && y >= 1 {
+				want = img.Red(euclidean.P2(x-2, y-1))
+			}
+			if shifted.Red(p) != want {
+				t.Errorf("at %s: expected the translation to give %#x, got %#x", p.ToString(), want, shifted.Red(p))
+			}
+		}
+	}
+
+	if _, err := img.Warp(euclidean.Scaling(0, 1).Homography(), 8, 6); !errors.Is(err, euclidean.ErrSingular) {
+		t.Errorf("expected %v, got %v", euclidean.ErrSingular, err)
+	}
+	if _, err := img.Warp(euclidean.Identity().Homography(), 0, 6); err == nil {
+		t.Error("expected an error on an empty size")
+	}
+}
+
+func TestRectify(t *testing.T) {
+	const w, h, cell = 120, 80, 20
+	img := imaging.New(checkerboard(w, h, cell))
+	// a screen photographed from its lower left
+	skew := euclidean.Homography{
+		{0.9, 0.15, 20},
+		{-0.1, 0.85, 30},
+		{-0.0008, 0.0006, 1},
+	}
+	photo, err := img.Warp(skew, 220, 180)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var corners [4]euclidean.Point
+	for idx, p := range []euclidean.PointF{euclidean.PF(0, 0), euclidean.PF(w, 0), euclidean.PF(w, h), euclidean.PF(0, h)} {
+		corners[idx] = skew.Apply(p).Round()
+	}
+	rectified, err := imaging.Rectify(photo, corners, w, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rectified.Width() != w || rectified.Height() != h {
+		t.Fatalf("expected %d x %d, got %d x %d", w, h, rectified.Width(), rectified.Height())
+	}
+	want, err := img.Integral()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := rectified.Integral()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the squares are compared away from their edges, which the two
+	// resamplings blur
+	for y := 0; y < h; y += cell {
+		for x := 0; x < w; x += cell {
+			b := euclidean.BoundAt(euclidean.P2(euclidean.X(x), euclidean.Y(y)), cell, cell).Inset(4, 4)
+			expected, err := want.Mean(color.ChannelGray, b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			mean, err := got.Mean(color.ChannelGray, b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(mean-expected) > 0.1*color.Max {
+				t.Errorf("square at %d, %d: expected a mean of %.0f, got %.0f", x, y, expected, mean)
+			}
+		}
+	}
+
+	aligned := [4]euclidean.Point{euclidean.P2(0, 0), euclidean.P2(5, 5), euclidean.P2(10, 10), euclidean.P2(0, 10)}
+	if _, err := imaging.Rectify(photo, aligned, w, h); !errors.Is(err, euclidean.ErrSingular) {
+		t.Errorf("expected %v on aligned corners, got %v", euclidean.ErrSingular, err)
+	}
+}
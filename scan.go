@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ramadoka/penguin-logic/pkg/euclidean"
+	"github.com/ramadoka/penguin-logic/pkg/imaging"
+)
+
+type boundJSON struct {
+	Left   int `json:"left"`
+	Top    int `json:"top"`
+	Right  int `json:"right"`
+	Bottom int `json:"bottom"`
+}
+
+type cellJSON struct {
+	// Bound is the window detected in the cell, or the one the lattice
+	// predicts when nothing was detected there.
+	Bound      boundJSON `json:"bound"`
+	Row        int       `json:"row"`
+	Col        int       `json:"col"`
+	Confidence float64   `json:"confidence"`
+	// Predicted marks cells the lattice expects but nothing was detected in.
+	Predicted bool          `json:"predicted,omitempty"`
+	Rarity    *rarityJSON   `json:"rarity,omitempty"`
+	Quantity  *quantityJSON `json:"quantity,omitempty"`
+	Item      *itemJSON     `json:"item,omitempty"`
+	// ExpiresInDays is only set when the expiry pill could be read.
+	ExpiresInDays *int        `json:"expiresInDays,omitempty"`
+	Expiry        *expiryJSON `json:"expiry,omitempty"`
+}
+
+type rarityJSON struct {
+	Tier       string  `json:"tier"`
+	Confidence float64 `json:"confidence"`
+}
+
+type quantityJSON struct {
+	Text       string  `json:"text"`
+	Value      int64   `json:"value"`
+	Confidence float64 `json:"confidence"`
+}
+
+type expiryJSON struct {
+	Style      string  `json:"style"`
+	Confidence float64 `json:"confidence"`
+}
+
+type itemJSON struct {
+	ID        string          `json:"id"`
+	Score     float64         `json:"score"`
+	RunnersUp []itemScoreJSON `json:"runnersUp"`
+}
+
+type itemScoreJSON struct {
+	ID    string  `json:"id"`
+	Score float64 `json:"score"`
+}
+
+type scanOptions struct {
+	window  int
+	stride  int
+	scales  []float64
+	overlap float64
+	order   imaging.DetectionOrder
+}
+
+type scanJSON struct {
+	Source string     `json:"source"`
+	Width  int        `json:"width"`
+	Height int        `json:"height"`
+	Cells  []cellJSON `json:"cells"`
+}
+
+func toBoundJSON(b euclidean.IBound) boundJSON {
+	return boundJSON{
+		Left:   int(b.Left()),
+		Top:    int(b.Top()),
+		Right:  int(b.Right()),
+		Bottom: int(b.Bottom()),
+	}
+}
+
+// scan detects the depot cells of the screenshot named in args and writes
+// them to out as JSON.
+func scan(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("scan", flag.ContinueOnError)
+	window := fs.Int("window", 130, "side of the square detection window, in pixels")
+	stride := fs.Int("stride", 32, "distance between two consecutive windows, in pixels")
+	scaleList := fs.String("scales", "1", "comma separated window scales, e.g. 0.75,1,1.25")
+	overlap := fs.Float64("overlap", 0.3, "IoU above which two detections are the same item")
+	keep := fs.String("keep", "score", "detection kept among overlapping ones: score or recentered")
+	icons := fs.String("icons", "", "directory of reference icons, named after their item ID, to identify items with")
+	maxPixels := fs.Int64("max-pixels", imaging.DefaultLimits.MaxPixels, "largest screenshot accepted, in pixels")
+	indent := fs.Bool("indent", false, "indent the JSON output")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("expected exactly one screenshot path, or - for stdin")
+	}
+	if *window <= 0 || *stride <= 0 {
+		return errors.New("window and stride must be positive")
+	}
+	scales, err := parseScales(*scaleList)
+	if err != nil {
+		return err
+	}
+	order, err := parseOrder(*keep)
+	if err != nil {
+		return err
+	}
+
+	var library imaging.IIconLibrary
+	if *icons != "" {
+		if library, err = imaging.LoadIconLibrary(*icons); err != nil {
+			return err
+		}
+	}
+
+	limits := imaging.DefaultLimits
+	limits.MaxPixels = *maxPixels
+	path := fs.Arg(0)
+	var img imaging.Image
+	if path == "-" {
+		img, err = limits.LoadReader(os.Stdin)
+	} else {
+		img, err = limits.Load(path)
+	}
+	if err != nil {
+		return err
+	}
+	placements, err := detect(img, scanOptions{
+		window:  *window,
+		stride:  *stride,
+		scales:  scales,
+		overlap: *overlap,
+		order:   order,
+	})
+	if err != nil {
+		return err
+	}
+	cells := make([]cellJSON, 0, len(placements))
+	for _, p := range placements {
+		cells = append(cells, describe(img, p, library))
+	}
+
+	enc := json.NewEncoder(out)
+	if *indent {
+		enc.SetIndent("", "  ")
+	}
+	return enc.Encode(scanJSON{
+		Source: path,
+		Width:  int(img.Width()),
+		Height: int(img.Height()),
+		Cells:  cells,
+	})
+}
+
+func parseScales(list string) ([]float64, error) {
+	scales := []float64{}
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		scale, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scale %q: %w", field, err)
+		}
+		if scale <= 0 {
+			return nil, fmt.Errorf("scale must be positive, got %v", scale)
+		}
+		scales = append(scales, scale)
+	}
+	if len(scales) == 0 {
+		return nil, fmt.Errorf("expected at least one scale, got %q", list)
+	}
+	return scales, nil
+}
+
+func parseOrder(keep string) (imaging.DetectionOrder, error) {
+	switch keep {
+	case "score":
+		return imaging.ByScore, nil
+	case "recentered":
+		return imaging.ByRecentering, nil
+	}
+	return nil, fmt.Errorf("unknown -keep value %q", keep)
+}
+
+// detect runs the default cell recognizer over the inverted screenshot, so
+// the dark item disks respond like bright blobs, keeps one detection per
+// item and lays them on the depot lattice.
+func detect(img imaging.Image, opts scanOptions) ([]imaging.Placement, error) {
+	scanner := imaging.Scanner(euclidean.W(opts.window), euclidean.H(opts.window), opts.stride, opts.scales...)
+	recognizer := imaging.Recognition(imaging.FeatInner5(), scanner)
+	detections, err := recognizer.Detect(img.Invert())
+	if err != nil {
+		return nil, err
+	}
+	detections = imaging.Suppress(detections, opts.overlap, opts.order)
+	if len(detections) == 0 {
+		return []imaging.Placement{}, nil
+	}
+	lattice, err := imaging.FitLattice(detections)
+	if err != nil {
+		return nil, err
+	}
+	return lattice.Place(detections), nil
+}
+
+// describe reads everything recognizable in a placed cell of the original
+// screenshot. Items are only identified when a library is given.
+func describe(img imaging.Image, p imaging.Placement, library imaging.IIconLibrary) cellJSON {
+	cell := cellJSON{
+		Bound:     toBoundJSON(p.Bound),
+		Row:       p.Cell.Row,
+		Col:       p.Cell.Col,
+		Predicted: p.Detection == nil,
+	}
+	if p.Detection != nil {
+		cell.Confidence = p.Detection.Score
+	}
+	if rarity, confidence := imaging.ClassifyRarity(img, p.CellBound); rarity != imaging.RarityUnknown {
+		cell.Rarity = &rarityJSON{Tier: rarity.String(), Confidence: confidence}
+	}
+	if quantity, err := imaging.ReadQuantity(img, p.CellBound); err == nil {
+		cell.Quantity = &quantityJSON{Text: quantity.Text, Value: quantity.Value, Confidence: quantity.Confidence}
+	}
+	if expiry, ok := imaging.DetectExpiry(img, p.CellBound); ok {
+		cell.Expiry = &expiryJSON{Style: expiry.Style.String(), Confidence: expiry.Confidence}
+		if expiry.Text != "" {
+			days := expiry.Days
+			cell.ExpiresInDays = &days
+		}
+	}
+	if library != nil {
+		if match, err := library.Match(img, p.CellBound); err == nil {
+			cell.Item = &itemJSON{ID: match.ID, Score: match.Score, RunnersUp: []itemScoreJSON{}}
+			for _, r := range match.RunnersUp {
+				cell.Item.RunnersUp = append(cell.Item.RunnersUp, itemScoreJSON{ID: r.ID, Score: r.Score})
+			}
+		}
+	}
+	return cell
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	c "image/color"
+
+	"github.com/ramadoka/penguin-logic/pkg/euclidean"
+	"github.com/ramadoka/penguin-logic/pkg/imaging"
+)
+
+const inputPath = "integrations/inputs/1.png"
+
+// writeBlank saves a w x h screenshot filled with fill and returns its path.
+func writeBlank(t *testing.T, w, h int, fill c.Color) string {
+	t.Helper()
+	src := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(src, src.Bounds(), image.NewUniform(fill), image.Point{}, draw.Src)
+	path := filepath.Join(t.TempDir(), "blank.png")
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	if err := png.Encode(out, src); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseScales(t *testing.T) {
+	for _, tc := range []struct {
+		list string
+		want []float64
+	}{
+		{"1", []float64{1}},
+		{" 0.75, 1,1.25 ", []float64{0.75, 1, 1.25}},
+		{"1,,2", []float64{1, 2}},
+	} {
+		got, err := parseScales(tc.list)
+		if err != nil || !slices.Equal(got, tc.want) {
+			t.Errorf("%q: expected %v, got %v (%v)", tc.list, tc.want, got, err)
+		}
+	}
+	for _, list := range []string{"", " , ", "0", "-1", "1,-0.5", "large"} {
+		if got, err := parseScales(list); err == nil {
+			t.Errorf("%q: expected an error, got %v", list, got)
+		}
+	}
+}
+
+func TestParseOrder(t *testing.T) {
+	for _, keep := range []string{"score", "recentered"} {
+		if order, err := parseOrder(keep); err != nil || order == nil {
+			t.Errorf("%q: expected an order, got %v", keep, err)
+		}
+	}
+	for _, keep := range []string{"", "best", "Score"} {
+		if _, err := parseOrder(keep); err == nil {
+			t.Errorf("%q: expected an error", keep)
+		}
+	}
+}
+
+func TestScanFlags(t *testing.T) {
+	path := writeBlank(t, 200, 150, c.White)
+	for name, args := range map[string][]string{
+		"bad keep":        {"-keep", "best", path},
+		"empty scales":    {"-scales", "", path},
+		"negative scales": {"-scales", "1,-1", path},
+		"zero window":     {"-window", "0", path},
+		"negative stride": {"-stride", "-32", path},
+		"unknown flag":    {"-depth", "8", path},
+		"no path":         {},
+		"two paths":       {path, path},
+	} {
+		out := &bytes.Buffer{}
+		if err := scan(args, out); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+		if out.Len() != 0 {
+			t.Errorf("%s: expected nothing written, got %s", name, out.String())
+		}
+	}
+}
+
+func TestScanBlank(t *testing.T) {
+	for _, fill := range []c.Color{c.White, c.Black} {
+		path := writeBlank(t, 400, 300, fill)
+		out := &bytes.Buffer{}
+		if err := scan([]string{path}, out); err != nil {
+			t.Fatal(err)
+		}
+		got := map[string]any{}
+		if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		cells, ok := got["cells"].([]any)
+		if len(got) != 4 || got["source"] != path || got["width"] != 400.0 || got["height"] != 300.0 || !ok || len(cells) != 0 {
+			t.Errorf("expected a 400 x 300 screenshot without cells, got %s", out.String())
+		}
+	}
+}
+
+func TestScanDepot(t *testing.T) {
+	out := &bytes.Buffer{}
+	if err := scan([]string{"-indent", inputPath}, out); err != nil {
+		t.Fatal(err)
+	}
+	var got scanJSON
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Source != inputPath || got.Width != 1205 || got.Height != 581 {
+		t.Errorf("unexpected header %q %d x %d", got.Source, got.Width, got.Height)
+	}
+	if len(got.Cells) != 24 {
+		t.Fatalf("expected the 24 cells of a 3 x 8 depot, got %d", len(got.Cells))
+	}
+	rarities, quantities := 0, 0
+	for _, cell := range got.Cells {
+		if cell.Rarity != nil && cell.Rarity.Tier != "" {
+			rarities++
+		}
+		if cell.Quantity != nil && cell.Quantity.Text != "" && cell.Quantity.Value > 0 {
+			quantities++
+		}
+		b := cell.Bound
+		if b.Right <= b.Left || b.Bottom <= b.Top || b.Left < 0 || b.Top < 0 || b.Right > got.Width || b.Bottom > got.Height {
+			t.Errorf("cell (%d, %d): bound %+v out of the screenshot", cell.Row, cell.Col, b)
+		}
+		if !cell.Predicted && (cell.Confidence <= 0 || cell.Confidence > 1) {
+			t.Errorf("cell (%d, %d): confidence %f out of (0, 1]", cell.Row, cell.Col, cell.Confidence)
+		}
+	}
+	if rarities == 0 || quantities == 0 {
+		t.Errorf("expected rarities and quantities read, got %d and %d", rarities, quantities)
+	}
+}
+
+// describe is checked field by field on a small fixture: a dark cell,
+// predicted or detected, holding nothing readable.
+func TestDescribe(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 160, 160))
+	draw.Draw(src, src.Bounds(), image.NewUniform(c.RGBA{40, 36, 44, 255}), image.Point{}, draw.Src)
+	img := imaging.New(src)
+	cellBound := euclidean.Bound(euclidean.P2(10, 20), euclidean.P2(140, 150))
+	detected := euclidean.Bound(euclidean.P2(12, 18), euclidean.P2(142, 148))
+	for _, tc := range []struct {
+		name      string
+		placement imaging.Placement
+		want      string
+	}{
+		{
+			name:      "predicted",
+			placement: imaging.Placement{Cell: imaging.LatticeCell{Row: 1, Col: 2}, Bound: cellBound, CellBound: cellBound},
+			want:      `{"bound":{"left":10,"top":20,"right":140,"bottom":150},"row":1,"col":2,"confidence":0,"predicted":true}`,
+		},
+		{
+			name: "detected",
+			placement: imaging.Placement{
+				Cell:      imaging.LatticeCell{Row: 0, Col: 3},
+				Bound:     detected,
+				CellBound: cellBound,
+				Detection: &imaging.Detection{Bound: detected, Score: 0.5},
+			},
+			want: `{"bound":{"left":12,"top":18,"right":142,"bottom":148},"row":0,"col":3,"confidence":0.5}`,
+		},
+	} {
+		got, err := json.Marshal(describe(img, tc.placement, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tc.want {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.want, got)
+		}
+	}
+}